@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/jinzhu/gorm"
 	"gopkg.in/gin-gonic/gin.v1"
 
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
@@ -15,12 +16,21 @@ type Article struct {
 	Title          string   `json:"title"`
 	Description    string   `json:"description"`
 	Body           string   `json:"body"`
+	BodyHTML       string   `json:"bodyHtml,omitempty"`
+	WordCount      int      `json:"wordCount"`
+	ReadingTime    int      `json:"readingTime"`
+	Excerpt        string   `json:"excerpt"`
 	Favorited      bool     `json:"favorited"`
 	FavoritesCount int      `json:"favoritesCount"`
+	CommentsCount  int      `json:"commentsCount"`
 	TagList        []string `json:"tagList"`
+	Status         string   `json:"status"`
+	Hidden         bool     `json:"hidden,omitempty"`
+	PublishedAt    string   `json:"publishedAt,omitempty"`
 	CreatedAt      string   `json:"createdAt"`
 	UpdatedAt      string   `json:"updatedAt"`
 	Author         Author   `json:"user"`
+	Snippet        string   `json:"snippet,omitempty"`
 }
 
 type Author struct {
@@ -37,6 +47,7 @@ type ArticleJSON struct {
 type ArticlesJSON struct {
 	Articles      []Article `json:"articles"`
 	ArticlesCount int       `json:"articlesCount"`
+	NextCursor    string    `json:"nextCursor,omitempty"`
 }
 
 func (h *Handler) extractArticle() gin.HandlerFunc {
@@ -44,9 +55,15 @@ func (h *Handler) extractArticle() gin.HandlerFunc {
 		if slug := c.Param("slug"); slug != "" {
 			a, err := h.DB.GetArticle(slug)
 
+			// Unpublished articles don't exist for anyone but their author
+			if u := getFromContext(currentUserKey, c).(*models.User); err == nil && !a.IsVisibleTo(u) {
+				err = gorm.ErrRecordNotFound
+			}
+
 			if err != nil {
 				c.Abort()
 				c.String(http.StatusNotFound, err.Error())
+				return
 			}
 
 			if a != nil {
@@ -58,6 +75,21 @@ func (h *Handler) extractArticle() gin.HandlerFunc {
 	}
 }
 
+// articleRoutes dispatch the GET /api/articles/:slug requests whose slug is one of the
+// given static routes (e.g. /api/articles/search), the router can't register them next
+// to the :slug wildcard.
+func articleRoutes(routes map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if route, present := routes[c.Param("slug")]; present {
+			c.Abort()
+			route(c)
+			return
+		}
+
+		c.Next()
+	}
+}
+
 func (h *Handler) getArticle(c *gin.Context) {
 	a := getFromContext(fetchedArticleKey, c).(*models.Article)
 
@@ -75,13 +107,40 @@ func (h *Handler) getArticles(c *gin.Context) {
 	var err error
 	var articles = []models.Article{}
 	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateFilters, models.ValidateArticlesSort) {
+		return
+	}
+
+	cursor, ok := extractCursor(c)
+	if !ok {
+		return
+	}
+
+	u := getFromContext(currentUserKey, c).(*models.User)
 	query := h.DB.GetAllArticles()
 
+	// Authors can list their own unpublished articles
+	if status := c.Request.Form.Get("status"); status != "" && status != models.StatusPublished {
+		if u.ID == 0 {
+			c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		query = h.DB.GetAllArticlesWithStatus(status, u)
+	}
+
 	query = h.DB.Limit(query, c.Request.Form)
 	query = h.DB.Offset(query, c.Request.Form)
+	query = h.DB.SortBy(query, c.Request.Form)
+	query = h.DB.After(query, cursor)
 	query = h.DB.FilterByTag(query, c.Request.Form)
 	query = h.DB.FilterAuthoredBy(query, c.Request.Form)
 	query = h.DB.FilterFavoritedBy(query, c.Request.Form)
+	query = h.DB.FilterCreatedBetween(query, c.Request.Form)
+	query = h.DB.ExcludeTag(query, c.Request.Form)
+	query = h.DB.ExcludeAuthoredBy(query, c.Request.Form)
+	query = h.DB.ExcludeHiddenAuthors(query, u)
 
 	err = query.Find(&articles).Error
 
@@ -95,8 +154,6 @@ func (h *Handler) getArticles(c *gin.Context) {
 		return
 	}
 
-	u := getFromContext(currentUserKey, c).(*models.User)
-
 	var articlesJSON ArticlesJSON
 	for i := range articles {
 		a := &articles[i]
@@ -105,6 +162,160 @@ func (h *Handler) getArticles(c *gin.Context) {
 
 	articlesJSON.ArticlesCount = len(articles)
 
+	if len(articles) == models.PageSize(c.Request.Form) {
+		if cursor := articles[len(articles)-1].Cursor(models.ArticlesSort(c.Request.Form)); cursor != nil {
+			articlesJSON.NextCursor = cursor.String()
+		}
+	}
+
+	c.JSON(http.StatusOK, articlesJSON)
+}
+
+// searchArticles handle GET /api/articles/search
+func (h *Handler) searchArticles(c *gin.Context) {
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateSearch) {
+		return
+	}
+
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	results, total, err := h.DB.SearchArticles(c.Request.Form.Get("q"), u, models.PageSize(c.Request.Form), models.PageOffset(c.Request.Form))
+
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if len(results) == 0 {
+		c.JSON(http.StatusOK, ArticlesJSON{ArticlesCount: total})
+		return
+	}
+
+	var articlesJSON ArticlesJSON
+	for i := range results {
+		article := h.buildArticleJSON(&results[i].Article, u)
+		article.Snippet = results[i].Snippet
+		articlesJSON.Articles = append(articlesJSON.Articles, article)
+	}
+
+	articlesJSON.ArticlesCount = total
+
+	c.JSON(http.StatusOK, articlesJSON)
+}
+
+// trendingArticles handle GET /api/articles/trending
+func (h *Handler) trendingArticles(c *gin.Context) {
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination) {
+		return
+	}
+
+	scores, err := h.Trending.Scores()
+
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	var ids []int
+	for _, score := range scores {
+		ids = append(ids, score.ArticleID)
+	}
+
+	// The scores are cached, the articles unlisted since or hidden from the user are left out before paging
+	ids, err = h.DB.ListedArticleIDs(ids, u)
+
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	offset := models.PageOffset(c.Request.Form)
+	limit := models.PageSize(c.Request.Form)
+
+	var page []int
+	if offset < len(ids) {
+		page = ids[offset:]
+	}
+	if len(page) > limit {
+		page = page[:limit]
+	}
+
+	articles, err := h.DB.GetArticlesByID(page)
+
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if len(articles) == 0 {
+		c.JSON(http.StatusOK, ArticlesJSON{ArticlesCount: len(ids)})
+		return
+	}
+
+	var articlesJSON ArticlesJSON
+	for i := range articles {
+		articlesJSON.Articles = append(articlesJSON.Articles, h.buildArticleJSON(&articles[i], u))
+	}
+
+	articlesJSON.ArticlesCount = len(ids)
+
+	c.JSON(http.StatusOK, articlesJSON)
+}
+
+// getFeed handle GET /api/articles/feed
+// It lists the articles of the users followed by the current user, newest first by default.
+func (h *Handler) getFeed(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	if u.ID == 0 {
+		c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
+
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateArticlesSort) {
+		return
+	}
+
+	cursor, ok := extractCursor(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.GetAllArticles()
+	query = h.DB.FilterFollowedBy(query, u)
+	query = h.DB.ExcludeHiddenAuthors(query, u)
+	query = h.DB.Limit(query, c.Request.Form)
+	query = h.DB.Offset(query, c.Request.Form)
+	query = h.DB.SortBy(query, c.Request.Form)
+	query = h.DB.After(query, cursor)
+
+	var articles []models.Article
+	if err := query.Find(&articles).Error; err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var articlesJSON = ArticlesJSON{Articles: []Article{}}
+	for i := range articles {
+		articlesJSON.Articles = append(articlesJSON.Articles, h.buildArticleJSON(&articles[i], u))
+	}
+
+	articlesJSON.ArticlesCount = len(articles)
+
+	if len(articles) == models.PageSize(c.Request.Form) {
+		if cursor := articles[len(articles)-1].Cursor(models.ArticlesSort(c.Request.Form)); cursor != nil {
+			articlesJSON.NextCursor = cursor.String()
+		}
+	}
+
 	c.JSON(http.StatusOK, articlesJSON)
 }
 
@@ -112,10 +323,12 @@ func (h *Handler) getArticles(c *gin.Context) {
 func (h *Handler) createArticle(c *gin.Context) {
 	var body struct {
 		Article struct {
-			Title       string   `json:"title"`
-			Description string   `json:"description"`
-			Body        string   `json:"body"`
-			TagList     []string `json:"tagList"`
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			Body        string     `json:"body"`
+			TagList     []string   `json:"tagList"`
+			Status      string     `json:"status"`
+			PublishedAt *time.Time `json:"publishedAt"`
 		} `json:"article"`
 	}
 
@@ -127,12 +340,22 @@ func (h *Handler) createArticle(c *gin.Context) {
 	u := getFromContext(currentUserKey, c).(*models.User)
 	a := models.NewArticle(body.Article.Title, body.Article.Description, body.Article.Body, u)
 
+	if body.Article.Status != "" {
+		a.Status = body.Article.Status
+		a.PublishedAt = body.Article.PublishedAt
+	}
+
 	if valid, errs := a.IsValid(); !valid {
 		errorJSON := errorJSON{errs}
 		c.JSON(http.StatusUnprocessableEntity, errorJSON)
 		return
 	}
 
+	flagged, ok := h.filterContent(c, a.Content())
+	if !ok {
+		return
+	}
+
 	for _, tagName := range body.Article.TagList {
 		tag, _ := h.DB.FindTagOrInit(tagName)
 		a.Tags = append(a.Tags, tag)
@@ -143,6 +366,8 @@ func (h *Handler) createArticle(c *gin.Context) {
 		return
 	}
 
+	h.flagContent(a, nil, flagged)
+
 	articleJSON := ArticleJSON{
 		Article: h.buildArticleJSON(a, u),
 	}
@@ -179,16 +404,40 @@ func (h *Handler) updateArticle(c *gin.Context) {
 
 	article = body["article"]
 
-	if title, present := article["title"]; present {
-		a.Title = title.(string)
+	errs := models.ValidationErrors{}
+
+	// stringField set the field to the string at key, when it is present
+	stringField := func(key string, field *string) {
+		if value, present := article[key]; present {
+			if s, ok := value.(string); ok {
+				*field = s
+			} else {
+				errs[key] = []string{models.NOT_STRING_MSG}
+			}
+		}
 	}
 
-	if description, present := article["description"]; present {
-		a.Description = description.(string)
+	stringField("title", &a.Title)
+	stringField("description", &a.Description)
+	stringField("body", &a.Body)
+	stringField("status", &a.Status)
+
+	// A null publishedAt clears the publication date
+	if publishedAt, present := article["publishedAt"]; present {
+		if publishedAt == nil {
+			a.PublishedAt = nil
+		} else if s, ok := publishedAt.(string); !ok {
+			errs["publishedAt"] = []string{models.NOT_STRING_MSG}
+		} else if t, err := time.Parse(time.RFC3339, s); err != nil {
+			errs["publishedAt"] = []string{models.INVALID_DATE_MSG}
+		} else {
+			a.PublishedAt = &t
+		}
 	}
 
-	if body, present := article["body"]; present {
-		a.Body = body.(string)
+	if len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{errs})
+		return
 	}
 
 	if valid, errs := a.IsValid(); !valid {
@@ -197,11 +446,18 @@ func (h *Handler) updateArticle(c *gin.Context) {
 		return
 	}
 
-	if err := h.DB.SaveArticle(a); err != nil {
+	flagged, ok := h.filterContent(c, a.Content())
+	if !ok {
+		return
+	}
+
+	if err := h.DB.SaveArticleRevision(a, u); err != nil {
 		c.String(http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
+	h.flagContent(a, nil, flagged)
+
 	articleJSON := ArticleJSON{
 		Article: h.buildArticleJSON(a, u),
 	}
@@ -232,23 +488,28 @@ func (h *Handler) deleteArticle(c *gin.Context) {
 }
 
 // favoriteArticle handle POST /api/articles/:slug/favorite
+// The users blocked by the author can't favorite the article.
 func (h *Handler) favoriteArticle(c *gin.Context) {
 	a := getFromContext(fetchedArticleKey, c).(*models.Article)
 	u := getFromContext(currentUserKey, c).(*models.User)
 
-	err := h.DB.FavoriteArticle(u, a)
-
-	articleJSON := ArticleJSON{
-		Article: h.buildArticleJSON(a, u),
+	if h.DB.IsBlocking(a.UserID, u.ID) {
+		c.String(http.StatusForbidden, models.BLOCKED_MSG)
+		return
 	}
 
-	code := http.StatusOK
+	err := h.DB.FavoriteArticle(u, a)
 
 	if err != nil {
-		code = http.StatusUnprocessableEntity
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	articleJSON := ArticleJSON{
+		Article: h.buildArticleJSON(a, u),
 	}
 
-	c.JSON(code, articleJSON)
+	c.JSON(http.StatusOK, articleJSON)
 }
 
 // unFavoriteArticle handle DELETE /api/articles/:slug/favorite
@@ -258,17 +519,16 @@ func (h *Handler) unFavoriteArticle(c *gin.Context) {
 
 	err := h.DB.UnfavoriteArticle(u, a)
 
-	articleJSON := ArticleJSON{
-		Article: h.buildArticleJSON(a, u),
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
 	}
 
-	code := http.StatusOK
-
-	if err != nil {
-		code = http.StatusUnprocessableEntity
+	articleJSON := ArticleJSON{
+		Article: h.buildArticleJSON(a, u),
 	}
 
-	c.JSON(code, articleJSON)
+	c.JSON(http.StatusOK, articleJSON)
 }
 
 func (h *Handler) buildArticleJSON(a *models.Article, u *models.User) Article {
@@ -285,8 +545,15 @@ func (h *Handler) buildArticleJSON(a *models.Article, u *models.User) Article {
 		Title:          a.Title,
 		Description:    a.Description,
 		Body:           a.Body,
+		BodyHTML:       a.RenderedBody(),
+		WordCount:      a.WordCount,
+		ReadingTime:    a.ReadingTime,
+		Excerpt:        a.Excerpt,
 		Favorited:      favorited,
 		FavoritesCount: a.FavoritesCount,
+		CommentsCount:  a.CommentsCount,
+		Status:         a.Status,
+		Hidden:         a.IsHidden(),
 		CreatedAt:      a.CreatedAt.Format(time.RFC3339),
 		UpdatedAt:      a.UpdatedAt.Format(time.RFC3339),
 		Author: Author{
@@ -297,6 +564,10 @@ func (h *Handler) buildArticleJSON(a *models.Article, u *models.User) Article {
 		},
 	}
 
+	if a.PublishedAt != nil {
+		article.PublishedAt = a.PublishedAt.Format(time.RFC3339)
+	}
+
 	for _, t := range a.Tags {
 		article.TagList = append(article.TagList, t.Name)
 	}
@@ -8,8 +8,13 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Machiel/slugify"
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/auth"
@@ -51,6 +56,9 @@ func TestMain(m *testing.M) {
 	j := auth.NewJWT()
 	h = New(db, j, logger)
 
+	// The tests send more requests than a client is allowed to
+	RateLimits = map[string]RateLimit{}
+
 	fixtures, err := testfixtures.NewFolder(DB.DB(), &testfixtures.SQLite{}, "../fixtures")
 	if err != nil {
 		log.Fatal(err)
@@ -60,6 +68,20 @@ func TestMain(m *testing.M) {
 		log.Fatal(err)
 	}
 
+	// Tables without fixtures would keep the rows of the previous runs
+	DB.Delete(models.Revision{})
+	DB.Delete(models.CommentVersion{})
+	DB.Delete(models.Report{})
+	DB.Delete(models.Follow{})
+	DB.Delete(models.Block{})
+	DB.Delete(models.Notification{})
+	DB.Delete(models.NotificationPreference{})
+
+	// Fixtures are inserted without the models callbacks
+	if err := db.Reconcile(); err != nil {
+		log.Fatal(err)
+	}
+
 	DB.Model(models.Article{}).
 		Preload("User").
 		Preload("Tags").
@@ -182,6 +204,397 @@ func TestArticlesHandler_FilterByFavorited(t *testing.T) {
 	}
 }
 
+func TestArticlesHandler_PaginateWithCursor(t *testing.T) {
+	var slugs []string
+	var cursor string
+
+	for page := 0; page < len(articles); page++ {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles?limit=2&cursor="+cursor, nil, nil)
+
+		if Code := recorder.Code; Code != http.StatusOK {
+			t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+		}
+
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+		for _, article := range articlesResponse.Articles {
+			slugs = append(slugs, article.Slug)
+		}
+
+		if cursor = articlesResponse.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	if len(slugs) != len(articles) {
+		t.Errorf("should return every article once: got %v want %v", len(slugs), len(articles))
+	}
+
+	for i, slug := range slugs {
+		if expected := articles[len(articles)-1-i].Slug; slug != expected {
+			t.Errorf("should return the articles in the correct order: got %v want %v", slug, expected)
+		}
+	}
+}
+
+func TestArticlesHandler_PaginateWithInvalidCursor(t *testing.T) {
+	recorder := makeRequest(t, http.MethodGet, "/api/articles?cursor=invalid", nil, nil)
+
+	if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should return a 422 status code: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	var errorJSON errorJSON
+	json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+	if _, present := errorJSON.Errors["cursor"]; !present {
+		t.Errorf("should return an error on the cursor param: got %v want %v", present, true)
+	}
+}
+
+func TestArticlesHandler_InvalidPagination(t *testing.T) {
+	queries := map[string]string{
+		"limit=abc":     "limit",
+		"limit=0":       "limit",
+		"limit=1000000": "limit",
+		"offset=abc":    "offset",
+		"offset=-1":     "offset",
+	}
+
+	for query, param := range queries {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles?"+query, nil, nil)
+
+		if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+			t.Errorf("%v should return a 422 status code: got %v want %v", query, Code, http.StatusUnprocessableEntity)
+		}
+
+		var errorJSON errorJSON
+		json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+		if _, present := errorJSON.Errors[param]; !present {
+			t.Errorf("%v should return an error on the %v param: got %v want %v", query, param, present, true)
+		}
+	}
+}
+
+func TestArticlesHandler_CombinedFilters(t *testing.T) {
+	after := url.QueryEscape(articles[0].CreatedAt.Format(time.RFC3339))
+	before := url.QueryEscape(articles[4].CreatedAt.Format(time.RFC3339))
+
+	queries := map[string]int{
+		"tag=tag1&tag=tag2":                    5,
+		"tag=tag1,tag2&tagMatch=all":           2,
+		"tag=tag1&tag=tag1&tagMatch=all":       5,
+		"tag=tag1,tag2,tag1,tag2&tagMatch=all": 2,
+		"tag=tag1&-tag=tag2":                   3,
+		"author=user1,user2":                   2,
+		"author=user1&author=user2":            2,
+		"-author=user1,user2":                  3,
+		"favorited=user6,user7":                5,
+		"createdAfter=" + after:                4,
+		"createdBefore=" + before:              4,
+		"createdAfter=" + after + "&createdBefore=" + before + "&-tag=tag2": 2,
+	}
+
+	for query, expected := range queries {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles?"+query, nil, nil)
+
+		if Code := recorder.Code; Code != http.StatusOK {
+			t.Errorf("%v should return a 200 status code: got %v want %v", query, Code, http.StatusOK)
+		}
+
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+		if count := len(articlesResponse.Articles); count != expected {
+			t.Errorf("%v should return the correct number article: got %v want %v", query, count, expected)
+		}
+	}
+}
+
+func TestArticlesHandler_InvalidFilters(t *testing.T) {
+	queries := map[string]string{
+		"createdAfter=yesterday":  "createdAfter",
+		"createdBefore=2017-13-1": "createdBefore",
+		"tagMatch=none":           "tagMatch",
+	}
+
+	for query, param := range queries {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles?"+query, nil, nil)
+
+		if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+			t.Errorf("%v should return a 422 status code: got %v want %v", query, Code, http.StatusUnprocessableEntity)
+		}
+
+		var errorJSON errorJSON
+		json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+		if _, present := errorJSON.Errors[param]; !present {
+			t.Errorf("%v should return an error on the %v param: got %v want %v", query, param, present, true)
+		}
+	}
+}
+
+func TestArticlesHandler_Search(t *testing.T) {
+	a := articles[2]
+	recorder := makeRequest(t, http.MethodGet, "/api/articles/search?q="+url.QueryEscape("body 3"), nil, nil)
+
+	if Code := recorder.Code; Code != http.StatusOK {
+		t.Errorf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+	if len(articlesResponse.Articles) != 1 {
+		t.Fatalf("should return the matching articles: got %v want %v", len(articlesResponse.Articles), 1)
+	}
+
+	if article := articlesResponse.Articles[0]; article.Slug != a.Slug {
+		t.Errorf("should return the correct article: got %v want %v", article.Slug, a.Slug)
+	}
+
+	if article := articlesResponse.Articles[0]; !strings.Contains(article.Snippet, "<mark>") {
+		t.Errorf("should return a highlighted snippet: got %v", article.Snippet)
+	}
+}
+
+func TestArticlesHandler_SearchWithoutQuery(t *testing.T) {
+	recorder := makeRequest(t, http.MethodGet, "/api/articles/search", nil, nil)
+
+	if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should return a 422 status code: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func TestArticlesHandler_SearchAndTrendingListedOnly(t *testing.T) {
+	author := articles[0].User
+	other := articles[1].User
+	readerHeader := tokenHeader("user6")
+
+	var created []*models.Article
+	create := func(user models.User, status string, hidden bool) *models.Article {
+		a := models.NewArticle(fmt.Sprintf("Zebracorn %v", len(created)), "Zebracorn description", "Zebracorn body", &user)
+		a.Status = status
+		if err := h.DB.CreateArticle(a); err != nil {
+			t.Fatal(err)
+		}
+		if hidden {
+			DB.Model(a).UpdateColumn("hidden_at", time.Now())
+		}
+
+		created = append(created, a)
+		return a
+	}
+
+	defer func() {
+		for _, a := range created {
+			h.DB.PurgeArticle(a)
+		}
+	}()
+
+	create(author, models.StatusDraft, false)
+	create(author, models.StatusPublished, true)
+	first := create(author, models.StatusPublished, false)
+	second := create(other, models.StatusPublished, false)
+	create(author, models.StatusDraft, false)
+	third := create(author, models.StatusPublished, false)
+
+	search := func(query string, header http.Header) ArticlesJSON {
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/search?q=zebracorn&"+query, nil, header).Body).Decode(&articlesResponse)
+		return articlesResponse
+	}
+
+	if page := search("limit=2", nil); len(page.Articles) != 2 || page.ArticlesCount != 3 {
+		t.Errorf("should only page through the listed articles: got %v articles of %v want 2 of 3", len(page.Articles), page.ArticlesCount)
+	}
+
+	if page := search("limit=2&offset=2", nil); len(page.Articles) != 1 || page.ArticlesCount != 3 {
+		t.Errorf("should return the last listed article: got %v articles of %v want 1 of 3", len(page.Articles), page.ArticlesCount)
+	}
+
+	makeRequest(t, http.MethodPost, "/api/profiles/"+other.Username+"/block", nil, readerHeader)
+	defer makeRequest(t, http.MethodDelete, "/api/profiles/"+other.Username+"/block", nil, readerHeader)
+
+	if page := search("limit=2", readerHeader); len(page.Articles) != 2 || page.ArticlesCount != 2 {
+		t.Errorf("should leave out the articles of the blocked users: got %v articles of %v want 2 of 2", len(page.Articles), page.ArticlesCount)
+	}
+
+	for _, a := range []*models.Article{first, second, third} {
+		f := models.Favorite{UserID: 7, ArticleID: a.ID}
+		if err := DB.Create(&f).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := h.Trending.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	trending := func(query string, header http.Header) ArticlesJSON {
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/trending?"+query, nil, header).Body).Decode(&articlesResponse)
+		return articlesResponse
+	}
+
+	total := trending("", nil).ArticlesCount
+
+	// The trending scores are cached, the article is hidden after they are computed
+	DB.Model(third).UpdateColumn("hidden_at", time.Now())
+
+	if page := trending(fmt.Sprintf("limit=%v", total-1), nil); len(page.Articles) != total-1 || page.ArticlesCount != total-1 {
+		t.Errorf("should leave out the hidden article before paging: got %v articles of %v want %v", len(page.Articles), page.ArticlesCount, total-1)
+	}
+
+	page := trending("limit=100", readerHeader)
+
+	if len(page.Articles) != page.ArticlesCount || page.ArticlesCount >= total-1 {
+		t.Errorf("should leave out the trending articles of the blocked users: got %v articles of %v", len(page.Articles), page.ArticlesCount)
+	}
+
+	for _, article := range page.Articles {
+		if article.Author.Username == other.Username {
+			t.Errorf("should not list the article of a blocked user: got %v", article.Slug)
+		}
+	}
+}
+
+func TestArticlesHandler_Sort(t *testing.T) {
+	recorder := makeRequest(t, http.MethodGet, "/api/articles?sort=oldest", nil, nil)
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+	if len(articlesResponse.Articles) != len(articles) {
+		t.Fatalf("should return every article: got %v want %v", len(articlesResponse.Articles), len(articles))
+	}
+
+	for i, article := range articlesResponse.Articles {
+		if expected := articles[i].Slug; article.Slug != expected {
+			t.Errorf("should return the oldest articles first: got %v want %v", article.Slug, expected)
+		}
+	}
+}
+
+func TestArticlesHandler_SortWithCursor(t *testing.T) {
+	for _, sort := range []string{"favorited", "commented"} {
+		var slugs []string
+		var cursor string
+
+		for page := 0; page < len(articles); page++ {
+			recorder := makeRequest(t, http.MethodGet, "/api/articles?sort="+sort+"&limit=2&cursor="+cursor, nil, nil)
+
+			if Code := recorder.Code; Code != http.StatusOK {
+				t.Fatalf("%v should return a 200 status code: got %v want %v", sort, Code, http.StatusOK)
+			}
+
+			var articlesResponse ArticlesJSON
+			json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+			for _, article := range articlesResponse.Articles {
+				slugs = append(slugs, article.Slug)
+			}
+
+			if cursor = articlesResponse.NextCursor; cursor == "" {
+				break
+			}
+		}
+
+		if len(slugs) != len(articles) {
+			t.Errorf("%v should return every article once: got %v want %v", sort, len(slugs), len(articles))
+		}
+	}
+}
+
+func TestArticlesHandler_SortUpdatedIgnoresComments(t *testing.T) {
+	author := articles[3].User
+
+	a := models.NewArticle("Updated Sort Article", "Updated Sort Article description", "Updated Sort Article body", &author)
+	if err := h.DB.CreateArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	updatedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	DB.Model(&models.Article{}).Where("id = ?", a.ID).UpdateColumn("updated_at", updatedAt)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Comment not updating the article"},
+	})
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", bytes.NewBuffer(jsonBody), tokenHeader("user6")).Code; Code != http.StatusCreated {
+		t.Fatalf("should return a 201 status code: got %v want %v", Code, http.StatusCreated)
+	}
+
+	var saved models.Article
+	DB.First(&saved, a.ID)
+
+	if !saved.UpdatedAt.Equal(updatedAt) {
+		t.Errorf("should not sort a commented article as updated: got %v want %v", saved.UpdatedAt, updatedAt)
+	}
+}
+
+func TestArticlesHandler_InvalidSort(t *testing.T) {
+	recorder := makeRequest(t, http.MethodGet, "/api/articles?limit=1", nil, nil)
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+	queries := map[string]string{
+		"sort=title": "sort",
+		"sort=oldest&cursor=" + articlesResponse.NextCursor:    "cursor",
+		"sort=commented&cursor=" + articlesResponse.NextCursor: "cursor",
+	}
+
+	for query, param := range queries {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles?"+query, nil, nil)
+
+		if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+			t.Errorf("%v should return a 422 status code: got %v want %v", query, Code, http.StatusUnprocessableEntity)
+		}
+
+		var errorJSON errorJSON
+		json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+		if _, present := errorJSON.Errors[param]; !present {
+			t.Errorf("%v should return an error on the %v param: got %v want %v", query, param, present, true)
+		}
+	}
+}
+
+func TestArticlesHandler_Trending(t *testing.T) {
+	a := articles[2]
+	u := articles[0].User
+
+	f := models.Favorite{UserID: u.ID, ArticleID: a.ID}
+	if err := DB.Create(&f).Error; err != nil {
+		t.Fatal(err)
+	}
+	defer DB.Delete(&f)
+
+	if err := h.Trending.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := makeRequest(t, http.MethodGet, "/api/articles/trending", nil, nil)
+
+	if Code := recorder.Code; Code != http.StatusOK {
+		t.Errorf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+	if len(articlesResponse.Articles) == 0 {
+		t.Fatalf("should return the trending articles: got %v want more than %v", len(articlesResponse.Articles), 0)
+	}
+
+	if article := articlesResponse.Articles[0]; article.Slug != a.Slug {
+		t.Errorf("should return the article with the most recent engagement first: got %v want %v", article.Slug, a.Slug)
+	}
+}
+
 func TestArticlesHandler_CreateUnauthorized(t *testing.T) {
 	a := Article{
 		Title:       "GoLang Web Services",
@@ -248,6 +661,76 @@ func TestArticlesHandler_Create(t *testing.T) {
 	}
 }
 
+func TestArticlesHandler_BodyHTML(t *testing.T) {
+	u := articles[0].User
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(u.Username))}}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]string{
+			"title":       "Markdown Article",
+			"description": "Markdown Article description",
+			"body":        "# Heading\n\n[link](javascript:alert(1)) <img src=x onerror=alert(1)>",
+		},
+	})
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), header).Body).Decode(&articleResponse)
+
+	expected := "<h1>Heading</h1>\n<p>link) &lt;img src=x onerror=alert(1)&gt;</p>"
+	if bodyHTML := articleResponse.Article.BodyHTML; bodyHTML != expected {
+		t.Errorf("should return the sanitized HTML of the body: got %v want %v", bodyHTML, expected)
+	}
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{
+		"article": map[string]string{"body": "*updated*"},
+	})
+
+	json.NewDecoder(makeRequest(t, http.MethodPut, "/api/articles/"+articleResponse.Article.Slug, bytes.NewBuffer(jsonBody), header).Body).Decode(&articleResponse)
+
+	var a models.Article
+	DB.Where("slug = ?", articleResponse.Article.Slug).First(&a)
+
+	if a.BodyHTML != "<p><em>updated</em></p>" {
+		t.Errorf("should store the HTML of the updated body: got %v want %v", a.BodyHTML, "<p><em>updated</em></p>")
+	}
+}
+
+func TestArticlesHandler_Summary(t *testing.T) {
+	u := articles[0].User
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(u.Username))}}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]string{
+			"title":       "Summarized Article",
+			"description": "Short",
+			"body":        strings.Repeat("**word** ", 250),
+		},
+	})
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), header).Body).Decode(&articleResponse)
+
+	article := articleResponse.Article
+	if article.WordCount != 250 {
+		t.Errorf("should return the word count: got %v want %v", article.WordCount, 250)
+	}
+
+	if article.ReadingTime != 2 {
+		t.Errorf("should return the reading time: got %v want %v", article.ReadingTime, 2)
+	}
+
+	if !strings.HasPrefix(article.Excerpt, "word word") || !strings.HasSuffix(article.Excerpt, "…") {
+		t.Errorf("should generate an excerpt from the body: got %v", article.Excerpt)
+	}
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles?author="+u.Username+"&limit=1", nil, nil).Body).Decode(&articlesResponse)
+
+	if len(articlesResponse.Articles) != 1 || articlesResponse.Articles[0].WordCount != 250 {
+		t.Errorf("should list the stored word count")
+	}
+}
+
 func TestArticlesHandler_CreateWithEmptyTitle(t *testing.T) {
 	a := articleEntity{
 		Article: article{
@@ -345,6 +828,34 @@ func TestArticlesHandler_CreateWithEmptyBody(t *testing.T) {
 	}
 }
 
+func TestArticlesHandler_ReservedTitle(t *testing.T) {
+	u := articles[0].User
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(u.Username))}}
+
+	for _, slug := range models.ReservedSlugs {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"article": map[string]string{"title": strings.ToUpper(slug[:1]) + slug[1:], "description": "Description", "body": "Body"},
+		})
+
+		recorder := makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), header)
+
+		var errorJSON errorJSON
+		json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+		if _, present := errorJSON.Errors["title"]; recorder.Code != http.StatusUnprocessableEntity || !present {
+			t.Errorf("should reject the title of the %v route: got %v want %v", slug, recorder.Code, http.StatusUnprocessableEntity)
+		}
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]string{"title": "Feed"},
+	})
+
+	if Code := makeRequest(t, http.MethodPut, "/api/articles/"+articles[0].Slug, bytes.NewBuffer(jsonBody), header).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not rename the article to a reserved slug: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
+
 func TestArticlesHandler_UpdateForbidden(t *testing.T) {
 	a := articles[0]
 	var u = models.User{}
@@ -461,13 +972,18 @@ func TestArticlesHandler_FavoriteAlreadyFavoritedArticle(t *testing.T) {
 	var articleResponse ArticleJSON
 	json.NewDecoder(recorder.Body).Decode(&articleResponse)
 
-	if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
-		t.Errorf("should get a 422 status code: got %v want %v", Code, http.StatusUnprocessableEntity)
+	if Code := recorder.Code; Code != http.StatusOK {
+		t.Errorf("should get a 200 status code: got %v want %v", Code, http.StatusOK)
 	}
 
 	if articleResponse.Article.Favorited != true {
 		t.Errorf("article should be in the same state: got %v want %v", articleResponse.Article.Favorited, true)
 	}
+
+	expectedCount := a.FavoritesCount
+	if articleResponse.Article.FavoritesCount != expectedCount {
+		t.Errorf("article favorites count should not be incremented: got %v want %v", articleResponse.Article.FavoritesCount, expectedCount)
+	}
 }
 
 func TestArticlesHandler_Unfavorite(t *testing.T) {
@@ -510,8 +1026,8 @@ func TestArticlesHandler_UnfavoriteNotYetFavoritedArticle(t *testing.T) {
 	var articleResponse ArticleJSON
 	json.NewDecoder(recorder.Body).Decode(&articleResponse)
 
-	if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
-		t.Errorf("should get a 422 status code: got %v want %v", Code, http.StatusUnprocessableEntity)
+	if Code := recorder.Code; Code != http.StatusOK {
+		t.Errorf("should get a 200 status code: got %v want %v", Code, http.StatusOK)
 	}
 
 	if articleResponse.Article.Favorited != false {
@@ -524,6 +1040,82 @@ func TestArticlesHandler_UnfavoriteNotYetFavoritedArticle(t *testing.T) {
 	}
 }
 
+func TestArticlesHandler_ConcurrentFavorite(t *testing.T) {
+	a := articles[4]
+	u := articles[3].User
+	const requests = 10
+
+	run := func(action func(*models.User, *models.Article) error) int {
+		var wg sync.WaitGroup
+		errs := make(chan error, requests)
+
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- action(&u, &models.Article{ID: a.ID})
+			}()
+		}
+
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			}
+		}
+		return succeeded
+	}
+
+	counts := func() (favorites int, favoritesCount int) {
+		DB.Model(&models.Favorite{}).Where("user_id = ? AND article_id = ?", u.ID, a.ID).Count(&favorites)
+
+		var article models.Article
+		DB.First(&article, a.ID)
+		return favorites, article.FavoritesCount
+	}
+
+	if succeeded := run(h.DB.FavoriteArticle); succeeded != requests {
+		t.Errorf("should accept every request: got %v want %v", succeeded, requests)
+	}
+
+	if favorites, favoritesCount := counts(); favorites != 1 || favoritesCount != a.FavoritesCount+1 {
+		t.Errorf("should count the favorite once: got %v favorites and a count of %v want %v and %v", favorites, favoritesCount, 1, a.FavoritesCount+1)
+	}
+
+	if succeeded := run(h.DB.UnfavoriteArticle); succeeded != requests {
+		t.Errorf("should accept every request: got %v want %v", succeeded, requests)
+	}
+
+	if favorites, favoritesCount := counts(); favorites != 0 || favoritesCount != a.FavoritesCount {
+		t.Errorf("should uncount the favorite once: got %v favorites and a count of %v want %v and %v", favorites, favoritesCount, 0, a.FavoritesCount)
+	}
+
+	if err := DB.Create(&models.Favorite{UserID: u.ID, ArticleID: a.ID}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DB.Create(&models.Favorite{UserID: u.ID, ArticleID: a.ID}).Error; err == nil {
+		t.Errorf("should not store a favorite twice")
+	}
+
+	DB.Model(&models.Article{}).Where("id = ?", a.ID).UpdateColumn("favorites_count", 0)
+
+	if err := h.DB.RefreshFavoritesCounts(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, favoritesCount := counts(); favoritesCount != a.FavoritesCount+1 {
+		t.Errorf("should recount the favorites: got %v want %v", favoritesCount, a.FavoritesCount+1)
+	}
+
+	if err := h.DB.UnfavoriteArticle(&u, &models.Article{ID: a.ID}); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestArticlesHandler_DeleteOk(t *testing.T) {
 	var u = &models.User{}
 	DB.Last(&u)
@@ -580,6 +1172,192 @@ func TestArticlesHandler_DeleteUnauthorized(t *testing.T) {
 	}
 }
 
+func TestArticlesHandler_DeleteCascade(t *testing.T) {
+	author := articles[0].User
+	reader := articles[1].User
+
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+	readerHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(reader.Username))}}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]interface{}{
+			"title":       "Cascade Article",
+			"description": "Cascade Article description",
+			"body":        "Cascade Article body",
+			"tagList":     []string{"cascade"},
+		},
+	})
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), authorHeader).Body).Decode(&articleResponse)
+	slug := articleResponse.Article.Slug
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Cascade comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+slug+"/comments", bytes.NewBuffer(jsonBody), readerHeader).Body).Decode(&commentResponse)
+	commentURL := "/api/articles/" + slug + "/comments/" + strconv.Itoa(commentResponse.Comment.ID)
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Edited cascade comment"},
+	})
+
+	makeRequest(t, http.MethodPut, commentURL, bytes.NewBuffer(jsonBody), readerHeader)
+	makeRequest(t, http.MethodPost, "/api/articles/"+slug+"/favorite", nil, readerHeader)
+	postReport(t, "/api/articles/"+slug+"/report", "spam", readerHeader)
+	postReport(t, commentURL+"/report", "spam", authorHeader)
+
+	a, err := h.DB.GetArticle(slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tag := models.Tag{Name: "cascade"}
+	DB.Where(tag).First(&tag)
+
+	if tag.TaggingsCount != 1 {
+		t.Errorf("should count the taggings of the tag: got %v want %v", tag.TaggingsCount, 1)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+slug, nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Fatalf("should get a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	DB.First(&tag, tag.ID)
+
+	if tag.TaggingsCount != 0 {
+		t.Errorf("should not count the taggings of deleted articles: got %v want %v", tag.TaggingsCount, 0)
+	}
+
+	if err := h.DB.PurgeArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"articles", "comments", "favorites", "taggings", "revisions", "reports", "notifications"} {
+		column := "article_id"
+		if table == "articles" {
+			column = "id"
+		}
+
+		var count int
+		DB.Table(table).Where(column+" = ?", a.ID).Count(&count)
+
+		if count != 0 {
+			t.Errorf("should not leave %v rows of the purged article: got %v want %v", table, count, 0)
+		}
+	}
+
+	var count int
+	DB.Model(&models.CommentVersion{}).Where("comment_id = ?", commentResponse.Comment.ID).Count(&count)
+
+	if count != 0 {
+		t.Errorf("should not leave comment_versions rows of the purged article: got %v want %v", count, 0)
+	}
+}
+
+func TestArticlesHandler_Drafts(t *testing.T) {
+	author := articles[0].User
+	reader := articles[1].User
+
+	a := articleEntity{
+		Article: article{
+			Title:       "Draft Article",
+			Description: "Draft Article description",
+			Body:        "Draft Article body",
+		},
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]interface{}{
+			"title":       a.Article.Title,
+			"description": a.Article.Description,
+			"body":        a.Article.Body,
+			"status":      models.StatusDraft,
+		},
+	})
+
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+	readerHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(reader.Username))}}
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), authorHeader)
+
+	if Code := recorder.Code; Code != http.StatusCreated {
+		t.Fatalf("should return a 201 status code: got %v want %v", Code, http.StatusCreated)
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(recorder.Body).Decode(&articleResponse)
+	slug := articleResponse.Article.Slug
+
+	if status := articleResponse.Article.Status; status != models.StatusDraft {
+		t.Errorf("should return the article status: got %v want %v", status, models.StatusDraft)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+slug, nil, readerHeader).Code; Code != http.StatusNotFound {
+		t.Errorf("should hide the draft to other users: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+slug, nil, authorHeader).Code; Code != http.StatusOK {
+		t.Errorf("should show the draft to its author: got %v want %v", Code, http.StatusOK)
+	}
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles?author="+author.Username, nil, authorHeader).Body).Decode(&articlesResponse)
+
+	for _, article := range articlesResponse.Articles {
+		if article.Slug == slug {
+			t.Errorf("should not list the draft with the published articles")
+		}
+	}
+
+	articlesResponse = ArticlesJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles?status=draft", nil, authorHeader).Body).Decode(&articlesResponse)
+
+	if len(articlesResponse.Articles) != 1 || articlesResponse.Articles[0].Slug != slug {
+		t.Errorf("should list the drafts of the author: got %v want %v", len(articlesResponse.Articles), 1)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles?status=draft", nil, nil).Code; Code != http.StatusUnauthorized {
+		t.Errorf("should not list drafts anonymously: got %v want %v", Code, http.StatusUnauthorized)
+	}
+}
+
+func TestArticlesHandler_ScheduledPublication(t *testing.T) {
+	var u = &models.User{}
+	DB.First(&u)
+
+	publishAt := time.Now().Add(time.Hour)
+	a := models.NewArticle("Scheduled Article", "Description", "Body", u)
+	a.Status = models.StatusScheduled
+	a.PublishedAt = &publishAt
+
+	if err := DB.Create(&a).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should hide the scheduled article: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if _, err := h.DB.PublishScheduledArticles(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should not publish the article before its time: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if _, err := h.DB.PublishScheduledArticles(publishAt.Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should publish the article at its time: got %v want %v", Code, http.StatusOK)
+	}
+}
+
 func TestArticlesHandler_ValidTokenButUserNotExist(t *testing.T) {
 	jwt := auth.NewJWT().NewToken("non-existing-username")
 
@@ -614,3 +1392,56 @@ func makeRequest(t *testing.T, method string, url string, body io.Reader, header
 
 	return recorder
 }
+
+func TestArticlesHandler_UpdateFieldTypes(t *testing.T) {
+	var u = &models.User{}
+	DB.First(&u)
+
+	publishAt := time.Now().Add(time.Hour)
+	a := models.NewArticle("Rescheduled Article", "Description", "Body", u)
+	a.Status = models.StatusScheduled
+	a.PublishedAt = &publishAt
+
+	if err := DB.Create(&a).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(u.Username))}}
+
+	update := func(fields map[string]interface{}) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(map[string]interface{}{"article": fields})
+		return makeRequest(t, http.MethodPut, "/api/articles/"+a.Slug, bytes.NewBuffer(jsonBody), header)
+	}
+
+	for _, fields := range []map[string]interface{}{
+		{"status": 1},
+		{"title": nil},
+		{"publishedAt": 1234},
+	} {
+		recorder := update(fields)
+
+		if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+			t.Errorf("%v should return a 422 status code: got %v want %v", fields, Code, http.StatusUnprocessableEntity)
+		}
+
+		var errorJSON errorJSON
+		json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+		for field := range fields {
+			if _, present := errorJSON.Errors[field]; !present {
+				t.Errorf("%v should return an error on the %v field: got %v", fields, field, errorJSON.Errors)
+			}
+		}
+	}
+
+	if Code := update(map[string]interface{}{"status": models.StatusDraft, "publishedAt": nil}).Code; Code != http.StatusOK {
+		t.Errorf("should accept a null publishedAt: got %v want %v", Code, http.StatusOK)
+	}
+
+	var updated models.Article
+	DB.First(&updated, a.ID)
+
+	if updated.Status != models.StatusDraft || updated.PublishedAt != nil {
+		t.Errorf("should clear the publication date: got %v %v", updated.Status, updated.PublishedAt)
+	}
+}
@@ -1,17 +1,27 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/markdown"
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"github.com/jinzhu/gorm"
 	"gopkg.in/gin-gonic/gin.v1"
 )
 
 type Comment struct {
 	ID        int    `json:"id"`
 	Body      string `json:"body"`
+	BodyHTML  string `json:"bodyHtml,omitempty"`
+	ParentID  *int   `json:"parentId"`
+	Depth     int    `json:"depth"`
+	Deleted   bool   `json:"deleted,omitempty"`
+	Hidden    bool   `json:"hidden,omitempty"`
+	Edited    bool   `json:"edited"`
+	EditedAt  string `json:"editedAt,omitempty"`
 	CreatedAt string `json:"createdAt"`
 	UpdatedAt string `json:"updatedAt"`
 	Author    Author `json:"author"`
@@ -22,12 +32,26 @@ type CommentJSON struct {
 }
 
 type CommentsJSON struct {
-	Comments []Comment `json:"comments"`
+	Comments      []Comment `json:"comments"`
+	CommentsCount int       `json:"commentsCount"`
+	NextCursor    string    `json:"nextCursor,omitempty"`
+}
+
+type CommentVersion struct {
+	Body       string `json:"body"`
+	BodyHTML   string `json:"bodyHtml"`
+	WrittenAt  string `json:"writtenAt"`
+	ReplacedAt string `json:"replacedAt"`
+}
+
+type CommentVersionsJSON struct {
+	Versions []CommentVersion `json:"versions"`
 }
 
 type commentBody struct {
 	Comment struct {
-		Body string `json:"body"`
+		Body     string `json:"body"`
+		ParentID *int   `json:"parentId"`
 	} `json:"comment"`
 }
 
@@ -35,36 +59,75 @@ func (h *Handler) getComments(c *gin.Context) {
 	a := getFromContext(fetchedArticleKey, c).(*models.Article)
 	u := getFromContext(currentUserKey, c).(*models.User)
 
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateCommentsSort) {
+		return
+	}
+
+	cursor, ok := extractCursor(c)
+	if !ok {
+		return
+	}
+
+	query := h.DB.SortCommentsBy(h.DB.GetAllComments(a), c.Request.Form)
+	query = h.DB.ExcludeHiddenCommenters(query, u)
+
+	// Comments are only paginated when asked to, so old clients
+	// keep receiving every comment of the article.
+	paginate := cursor != nil || c.Request.Form.Get("limit") != "" || c.Request.Form.Get("offset") != ""
+	if paginate {
+		query = h.DB.Limit(query, c.Request.Form)
+		query = h.DB.Offset(query, c.Request.Form)
+		query = h.DB.CommentsAfter(query, cursor)
+	}
+
 	var comments []models.Comment
-	err := h.DB.GetComments(a, &comments)
+	err := query.Find(&comments).Error
 
 	if err != nil {
 		c.String(http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
-	var commentsJSON = CommentsJSON{}
+	count, err := h.DB.CountComments(a)
+
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	// The comments of the blocked or muted users are only listed for their replies
+	hidden := h.hiddenUsers(u)
+
+	var commentsJSON = CommentsJSON{CommentsCount: count}
 	for _, comment := range comments {
-		commentsJSON.Comments = append(commentsJSON.Comments, h.buildCommentJSON(&comment, u))
+		commentJSON := h.buildCommentJSON(&comment, u)
+		if comment.IsDeleted() {
+			commentJSON = deletedCommentJSON(commentJSON)
+		} else if !comment.IsVisibleTo(u) || hidden[comment.UserID] {
+			commentJSON = hiddenCommentJSON(commentJSON)
+		}
+		commentsJSON.Comments = append(commentsJSON.Comments, commentJSON)
+	}
+
+	if paginate && len(comments) == models.PageSize(c.Request.Form) {
+		commentsJSON.NextCursor = comments[len(comments)-1].Cursor(models.CommentsSort(c.Request.Form)).String()
 	}
 
 	c.JSON(http.StatusOK, commentsJSON)
 }
 
 func (h *Handler) getComment(c *gin.Context) {
-	commentID, _ := strconv.Atoi(c.Param("commentID"))
 	u := getFromContext(currentUserKey, c).(*models.User)
 
-	var comment models.Comment
-	err := h.DB.GetComment(commentID, &comment)
-
-	if err != nil {
-		c.String(http.StatusNotFound, err.Error())
+	comment, ok := h.extractComment(c)
+	if !ok {
 		return
 	}
 
 	var commentJSON = CommentJSON{
-		Comment: h.buildCommentJSON(&comment, u),
+		Comment: h.buildCommentJSON(comment, u),
 	}
 
 	c.JSON(http.StatusOK, commentJSON)
@@ -80,8 +143,23 @@ func (h *Handler) addComment(c *gin.Context) {
 		return
 	}
 
+	// The users blocked by the author can't comment their articles
+	if h.DB.IsBlocking(a.UserID, u.ID) {
+		c.String(http.StatusForbidden, models.BLOCKED_MSG)
+		return
+	}
+
 	newComment, errs := models.NewComment(a, u, commentBody.Comment.Body)
 
+	if errs == nil && commentBody.Comment.ParentID != nil {
+		var parent models.Comment
+		if err := h.DB.GetComment(a, *commentBody.Comment.ParentID, &parent); err != nil {
+			errs = models.ValidationErrors{"parentId": []string{models.PARENT_NOT_FOUND_MSG}}
+		} else {
+			errs = newComment.ReplyTo(&parent)
+		}
+	}
+
 	if errs != nil {
 		errorJSON := errorJSON{errs}
 
@@ -89,6 +167,11 @@ func (h *Handler) addComment(c *gin.Context) {
 		return
 	}
 
+	flagged, ok := h.filterContent(c, newComment.Content())
+	if !ok {
+		return
+	}
+
 	err := h.DB.CreateComment(newComment)
 
 	if err != nil {
@@ -96,6 +179,14 @@ func (h *Handler) addComment(c *gin.Context) {
 		return
 	}
 
+	h.flagContent(a, newComment, flagged)
+
+	// The flagged comments are pushed once a moderator dismisses their report, see resolveReport.
+	// The stream is shared by every reader, they see the comment as an anonymous user.
+	if flagged == nil {
+		h.publishComment(a, commentCreatedEvent, newComment.UserID, h.buildCommentJSON(newComment, &models.User{}))
+	}
+
 	commentJSON := CommentJSON{
 		Comment: h.buildCommentJSON(newComment, u),
 	}
@@ -104,14 +195,11 @@ func (h *Handler) addComment(c *gin.Context) {
 }
 
 func (h *Handler) deleteComment(c *gin.Context) {
-	commentID, _ := strconv.Atoi(c.Param("commentID"))
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
 	u := getFromContext(currentUserKey, c).(*models.User)
 
-	var comment = models.Comment{}
-	err := h.DB.GetComment(commentID, &comment)
-
-	if err != nil {
-		c.String(http.StatusNotFound, err.Error())
+	comment, ok := h.extractComment(c)
+	if !ok {
 		return
 	}
 
@@ -120,16 +208,134 @@ func (h *Handler) deleteComment(c *gin.Context) {
 		return
 	}
 
-	err = h.DB.DeleteComment(&comment)
+	err := h.DB.DeleteComment(comment)
 
 	if err != nil {
 		c.String(http.StatusUnprocessableEntity, err.Error())
 		return
 	}
 
+	h.publishComment(a, commentDeletedEvent, comment.UserID, deletedCommentEvent{comment.ID})
+
 	c.String(http.StatusNoContent, http.StatusText(http.StatusNoContent))
 }
 
+func (h *Handler) updateComment(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	comment, ok := h.extractComment(c)
+	if !ok {
+		return
+	}
+
+	if canEdit := comment.CanBeEditedBy(u); !canEdit {
+		c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+
+	if !comment.IsEditableAt(time.Now()) {
+		c.String(http.StatusForbidden, fmt.Sprintf(models.EDIT_WINDOW_MSG, models.CommentEditWindow))
+		return
+	}
+
+	var commentBody commentBody
+	if err := c.BindJSON(&commentBody); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if errs := models.ValidateCommentBody(commentBody.Comment.Body); errs != nil {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{errs})
+		return
+	}
+
+	content := comment.Content()
+	content.Fields["body"] = commentBody.Comment.Body
+
+	flagged, ok := h.filterContent(c, content)
+	if !ok {
+		return
+	}
+
+	err := h.DB.UpdateComment(comment, commentBody.Comment.Body)
+
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	h.flagContent(a, comment, flagged)
+
+	commentJSON := CommentJSON{
+		Comment: h.buildCommentJSON(comment, u),
+	}
+
+	c.JSON(http.StatusOK, commentJSON)
+}
+
+// getCommentVersions list the previous bodies of an edited comment, only the moderators can see them.
+func (h *Handler) getCommentVersions(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	if !u.IsModerator() {
+		c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+		return
+	}
+
+	comment, ok := h.extractComment(c)
+	if !ok {
+		return
+	}
+
+	var versions []models.CommentVersion
+	if err := h.DB.GetCommentVersions(comment, &versions); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var versionsJSON = CommentVersionsJSON{Versions: []CommentVersion{}}
+	for _, version := range versions {
+		versionsJSON.Versions = append(versionsJSON.Versions, CommentVersion{
+			Body:       version.Body,
+			BodyHTML:   markdown.Render(version.Body),
+			WrittenAt:  version.WrittenAt.Format(time.RFC3339),
+			ReplacedAt: version.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	c.JSON(http.StatusOK, versionsJSON)
+}
+
+// extractComment get the comment with the :commentID param of the fetched article,
+// it writes a 400 or a 404 response and returns false when there is none.
+func (h *Handler) extractComment(c *gin.Context) (*models.Comment, bool) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return nil, false
+	}
+
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	var comment models.Comment
+	err = h.DB.GetComment(a, commentID, &comment)
+
+	// Hidden comments don't exist for anyone but their author and the moderators
+	if err == nil && !comment.IsVisibleTo(u) {
+		err = gorm.ErrRecordNotFound
+	}
+
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return &comment, true
+}
+
 func (h *Handler) buildCommentJSON(c *models.Comment, u *models.User) Comment {
 	following := false
 
@@ -137,9 +343,20 @@ func (h *Handler) buildCommentJSON(c *models.Comment, u *models.User) Comment {
 		following = h.DB.IsFollowing(u.ID, c.User.ID)
 	}
 
+	editedAt := ""
+	if c.IsEdited() {
+		editedAt = c.EditedAt.Format(time.RFC3339)
+	}
+
 	return Comment{
 		ID:        c.ID,
 		Body:      c.Body,
+		BodyHTML:  c.RenderedBody(),
+		ParentID:  c.ParentID,
+		Depth:     c.Depth,
+		Hidden:    c.IsHidden(),
+		Edited:    c.IsEdited(),
+		EditedAt:  editedAt,
 		CreatedAt: c.CreatedAt.Format(time.RFC3339),
 		UpdatedAt: c.UpdatedAt.Format(time.RFC3339),
 		Author: Author{
@@ -150,3 +367,27 @@ func (h *Handler) buildCommentJSON(c *models.Comment, u *models.User) Comment {
 		},
 	}
 }
+
+// deletedCommentJSON hide the content of a deleted comment
+// listed to keep its replies threaded.
+func deletedCommentJSON(comment Comment) Comment {
+	comment.Body = models.DeletedCommentBody
+	comment.BodyHTML = ""
+	comment.Author = Author{}
+	comment.Deleted = true
+	comment.Edited = false
+	comment.EditedAt = ""
+
+	return comment
+}
+
+// hiddenCommentJSON hide the content of a comment hidden by the moderators
+// listed to keep its replies threaded.
+func hiddenCommentJSON(comment Comment) Comment {
+	comment = deletedCommentJSON(comment)
+	comment.Body = models.HiddenCommentBody
+	comment.Deleted = false
+	comment.Hidden = true
+
+	return comment
+}
@@ -5,8 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
 )
@@ -40,6 +42,72 @@ func Test_GetComments(t *testing.T) {
 	}
 }
 
+func Test_GetCommentsWithCursor(t *testing.T) {
+	a := articles[0]
+
+	recorder := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments?limit=2", nil, nil)
+	var firstPage CommentsJSON
+	json.NewDecoder(recorder.Body).Decode(&firstPage)
+
+	if count := len(firstPage.Comments); count != 2 {
+		t.Fatalf("should return the requested number of comments: got %v want %v", count, 2)
+	}
+
+	if firstPage.NextCursor == "" {
+		t.Fatalf("should return a cursor to the next page: got %v want a cursor", firstPage.NextCursor)
+	}
+
+	recorder = makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments?limit=2&cursor="+firstPage.NextCursor, nil, nil)
+	var secondPage CommentsJSON
+	json.NewDecoder(recorder.Body).Decode(&secondPage)
+
+	expectedCount := len(a.Comments) - 2
+	if count := len(secondPage.Comments); count != expectedCount {
+		t.Errorf("should return the remaining comments: got %v want %v", count, expectedCount)
+	}
+
+	for _, comment := range secondPage.Comments {
+		for _, seen := range firstPage.Comments {
+			if comment.ID == seen.ID {
+				t.Errorf("should not return a comment twice: got %v", comment.ID)
+			}
+		}
+	}
+}
+
+func Test_GetCommentsSortedAndPaginated(t *testing.T) {
+	a := articles[0]
+
+	var oldest, newest CommentsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments", nil, nil).Body).Decode(&oldest)
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments?sort=newest", nil, nil).Body).Decode(&newest)
+
+	if oldest.CommentsCount != len(oldest.Comments) || newest.CommentsCount != oldest.CommentsCount {
+		t.Fatalf("should return the total number of comments: got %v want %v", newest.CommentsCount, len(oldest.Comments))
+	}
+
+	for i, comment := range newest.Comments {
+		if expected := oldest.Comments[len(oldest.Comments)-1-i].ID; comment.ID != expected {
+			t.Errorf("should list the newest comments first: got %v want %v", comment.ID, expected)
+		}
+	}
+
+	var page CommentsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments?sort=newest&limit=1&offset=1", nil, nil).Body).Decode(&page)
+
+	if len(page.Comments) != 1 || page.Comments[0].ID != newest.Comments[1].ID {
+		t.Errorf("should skip the offset comments: got %v want %v", page.Comments, newest.Comments[1].ID)
+	}
+
+	if page.CommentsCount != newest.CommentsCount {
+		t.Errorf("should count every comment of the article: got %v want %v", page.CommentsCount, newest.CommentsCount)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments?sort=popular", nil, nil).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject an unknown sort: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
+
 func Test_GetComment(t *testing.T) {
 	recorder := makeRequest(t, http.MethodGet, "/api/articles/"+articles[0].Slug+"/comments/1", nil, nil)
 
@@ -77,6 +145,20 @@ func Test_GetCommentNotFound(t *testing.T) {
 	}
 }
 
+func Test_GetCommentOfAnotherArticle(t *testing.T) {
+	commentID := strconv.Itoa(articles[0].Comments[0].ID)
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+articles[1].Slug+"/comments/"+commentID, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should not return the comment of another article: got %v want %v", Code, http.StatusNotFound)
+	}
+}
+
+func Test_GetCommentBadID(t *testing.T) {
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+articles[0].Slug+"/comments/first", nil, nil).Code; Code != http.StatusBadRequest {
+		t.Errorf("should return a 400 status code: got %v want %v", Code, http.StatusBadRequest)
+	}
+}
+
 func Test_PostCommentOK(t *testing.T) {
 	commentBody := map[string]interface{}{
 		"comment": map[string]string{
@@ -123,6 +205,27 @@ func Test_PostCommentOK(t *testing.T) {
 	}
 }
 
+func Test_PostCommentRendersMarkdown(t *testing.T) {
+	a := articles[0]
+	u := articles[1].User
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "**Nice** <script>alert(1)</script>"},
+	})
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", bytes.NewBuffer(jsonBody), http.Header{
+		"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(u.Username))},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(recorder.Body).Decode(&commentResponse)
+
+	expected := "<p><strong>Nice</strong> &lt;script&gt;alert(1)&lt;/script&gt;</p>"
+	if bodyHTML := commentResponse.Comment.BodyHTML; bodyHTML != expected {
+		t.Errorf("should return the sanitized HTML of the body: got %v want %v", bodyHTML, expected)
+	}
+}
+
 func Test_PostCommentEmptyBody(t *testing.T) {
 	commentBody := map[string]interface{}{
 		"comment": map[string]string{
@@ -190,10 +293,27 @@ func Test_DeleteCommentNotFound(t *testing.T) {
 	}
 }
 
+func Test_DeleteCommentOfAnotherArticle(t *testing.T) {
+	comment := articles[1].Comments[0]
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(comment.User.Username))}}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+articles[0].Slug+"/comments/"+strconv.Itoa(comment.ID), nil, header).Code; Code != http.StatusNotFound {
+		t.Errorf("should return a 404 status code: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if err := DB.First(&models.Comment{}, comment.ID).Error; err != nil {
+		t.Errorf("should not delete the comment of another article: got %v", err)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+articles[1].Slug+"/comments/first", nil, header).Code; Code != http.StatusBadRequest {
+		t.Errorf("should return a 400 status code: got %v want %v", Code, http.StatusBadRequest)
+	}
+}
+
 func Test_DeleteCommentForbidden(t *testing.T) {
 	jwt := h.JWT.NewToken("user1")
 
-	recorder := makeRequest(t, http.MethodDelete, "/api/articles/"+articles[2].Slug+"/comments/1", nil, http.Header{
+	recorder := makeRequest(t, http.MethodDelete, "/api/articles/"+articles[0].Slug+"/comments/1", nil, http.Header{
 		"Authorization": []string{fmt.Sprintf("Token %s", jwt)},
 	})
 
@@ -222,8 +342,242 @@ func Test_DeleteCommentOK(t *testing.T) {
 		t.Errorf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
 	}
 
-	err := DB.First(&Comment{ID: commentID}).Error
+	err := DB.First(&models.Comment{}, commentID).Error
 	if got := (err == nil); got {
 		t.Errorf("should delete the comment got %v want %v", got, true)
 	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+article.Slug+"/comments/"+strconv.Itoa(commentID), nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should not return a deleted comment: got %v want %v", Code, http.StatusNotFound)
+	}
+}
+
+func Test_CommentReplies(t *testing.T) {
+	article := articles[3]
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(article.User.Username))}}
+
+	postComment := func(slug string, body string, parentID interface{}) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]interface{}{"body": body, "parentId": parentID},
+		})
+		return makeRequest(t, http.MethodPost, "/api/articles/"+slug+"/comments", bytes.NewBuffer(jsonBody), header)
+	}
+
+	var parent, reply CommentJSON
+	json.NewDecoder(postComment(article.Slug, "Parent", nil).Body).Decode(&parent)
+	json.NewDecoder(postComment(article.Slug, "Reply", parent.Comment.ID).Body).Decode(&reply)
+
+	if reply.Comment.ParentID == nil || *reply.Comment.ParentID != parent.Comment.ID || reply.Comment.Depth != 1 {
+		t.Fatalf("should reply to the parent comment: got %v want %v", reply.Comment.ParentID, parent.Comment.ID)
+	}
+
+	if Code := postComment(articles[2].Slug, "Elsewhere", parent.Comment.ID).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not reply to a comment of another article: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	maxDepth := models.MaxCommentDepth
+	models.MaxCommentDepth = 1
+
+	recorder := postComment(article.Slug, "Too deep", reply.Comment.ID)
+
+	models.MaxCommentDepth = maxDepth
+
+	var errorResponse errorJSON
+	json.NewDecoder(recorder.Body).Decode(&errorResponse)
+
+	if _, ok := errorResponse.Errors["parentId"]; recorder.Code != http.StatusUnprocessableEntity || !ok {
+		t.Errorf("should not nest the replies deeper than the max depth: got %v want %v", recorder.Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+article.Slug+"/comments/"+strconv.Itoa(parent.Comment.ID), nil, header).Code; Code != http.StatusNoContent {
+		t.Fatalf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	var commentsResponse CommentsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+article.Slug+"/comments", nil, nil).Body).Decode(&commentsResponse)
+
+	var placeholder, child *Comment
+	for i, comment := range commentsResponse.Comments {
+		switch comment.ID {
+		case parent.Comment.ID:
+			placeholder = &commentsResponse.Comments[i]
+		case reply.Comment.ID:
+			child = &commentsResponse.Comments[i]
+		}
+	}
+
+	if placeholder == nil || !placeholder.Deleted || placeholder.Body != models.DeletedCommentBody || placeholder.Author.Username != "" {
+		t.Errorf("should list the deleted parent as a placeholder: got %v", placeholder)
+	}
+
+	if child == nil {
+		t.Errorf("should keep listing the replies of a deleted comment")
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+article.Slug, nil, nil).Body).Decode(&articleResponse)
+
+	if count := commentsResponse.CommentsCount; count != articleResponse.Article.CommentsCount || count != len(commentsResponse.Comments)-1 {
+		t.Errorf("should not count the placeholder: got %v want %v", count, len(commentsResponse.Comments)-1)
+	}
+
+	if Code := postComment(article.Slug, "Reply to deleted", parent.Comment.ID).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not reply to a deleted comment: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func Test_UpdateComment(t *testing.T) {
+	article := articles[3]
+	author := article.User
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(author.Username))}}
+	otherHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(articles[2].User.Username))}}
+	moderatorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken("user5"))}}
+
+	commentBody := func(body string) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]string{"body": body},
+		})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	var created CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", commentBody("First version"), authorHeader).Body).Decode(&created)
+	url := "/api/articles/" + article.Slug + "/comments/" + strconv.Itoa(created.Comment.ID)
+
+	if created.Comment.Edited {
+		t.Errorf("should not flag a new comment as edited")
+	}
+
+	if Code := makeRequest(t, http.MethodPut, url, commentBody("Hijacked"), otherHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should not let another user edit the comment: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	if Code := makeRequest(t, http.MethodPut, url, commentBody(""), authorHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not accept an empty body: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	recorder := makeRequest(t, http.MethodPut, url, commentBody("Second **version**"), authorHeader)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	var updated CommentJSON
+	json.NewDecoder(recorder.Body).Decode(&updated)
+
+	if updated.Comment.Body != "Second **version**" || !updated.Comment.Edited || updated.Comment.EditedAt == "" {
+		t.Errorf("should return the edited comment: got %v", updated.Comment)
+	}
+
+	if want := "<p>Second <strong>version</strong></p>"; updated.Comment.BodyHTML != want {
+		t.Errorf("should render the new body: got %v want %v", updated.Comment.BodyHTML, want)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, url+"/versions", nil, authorHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should only show the versions to the moderators: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	var versionsResponse CommentVersionsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, url+"/versions", nil, moderatorHeader).Body).Decode(&versionsResponse)
+
+	if len(versionsResponse.Versions) != 1 || versionsResponse.Versions[0].Body != "First version" {
+		t.Errorf("should keep the previous body: got %v", versionsResponse.Versions)
+	}
+
+	DB.Model(&models.Comment{}).Where("id = ?", created.Comment.ID).UpdateColumn("created_at", time.Now().Add(-models.CommentEditWindow-time.Minute))
+
+	if Code := makeRequest(t, http.MethodPut, url, commentBody("Too late"), authorHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should not edit a comment after the edit window: got %v want %v", Code, http.StatusForbidden)
+	}
+}
+
+func Test_CommentsCount(t *testing.T) {
+	article := articles[4]
+	header := http.Header{"Authorization": []string{fmt.Sprintf("Token %s", h.JWT.NewToken(article.User.Username))}}
+
+	commentsCount := func() int {
+		var articleResponse ArticleJSON
+		json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+article.Slug, nil, nil).Body).Decode(&articleResponse)
+		return articleResponse.Article.CommentsCount
+	}
+
+	count := commentsCount()
+	if count != len(article.Comments) {
+		t.Errorf("should count the comments of the article: got %v want %v", count, len(article.Comments))
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Counted comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), header).Body).Decode(&commentResponse)
+	commentID := strconv.Itoa(commentResponse.Comment.ID)
+
+	if got := commentsCount(); got != count+1 {
+		t.Errorf("should count a new comment: got %v want %v", got, count+1)
+	}
+
+	makeRequest(t, http.MethodDelete, "/api/articles/"+article.Slug+"/comments/"+commentID, nil, header)
+
+	if got := commentsCount(); got != count {
+		t.Errorf("should not count a deleted comment: got %v want %v", got, count)
+	}
+
+	makeRequest(t, http.MethodPost, "/api/trash/comments/"+commentID+"/restore", nil, header)
+
+	if got := commentsCount(); got != count+1 {
+		t.Errorf("should count a restored comment: got %v want %v", got, count+1)
+	}
+
+	DB.Model(&models.Article{}).Where("id = ?", article.ID).UpdateColumn("comments_count", 0)
+
+	if err := h.DB.RefreshCommentsCounts(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := commentsCount(); got != count+1 {
+		t.Errorf("should recount the comments: got %v want %v", got, count+1)
+	}
+}
+
+func Test_CreateCommentKeepsArticle(t *testing.T) {
+	author := articles[1].User
+
+	created := models.NewArticle("Commented Article", "Commented Article description", "Commented Article body", &author)
+	if err := h.DB.CreateArticle(created); err != nil {
+		t.Fatal(err)
+	}
+
+	// The article is loaded at the start of the request, its favorites change before the comment is saved
+	a, err := h.DB.GetArticle(created.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DB.Model(&models.Article{}).Where("id = ?", a.ID).UpdateColumn("favorites_count", 99)
+
+	commenter, err := h.DB.FindUserByUsername("user6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comment, errs := models.NewComment(a, commenter, "Comment on a stale article")
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	if err := h.DB.CreateComment(comment); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved models.Article
+	DB.First(&saved, a.ID)
+
+	if saved.FavoritesCount != 99 {
+		t.Errorf("should not overwrite the favorites count of the article: got %v want %v", saved.FavoritesCount, 99)
+	}
+
+	if saved.CommentsCount != a.CommentsCount+1 {
+		t.Errorf("should count the comment: got %v want %v", saved.CommentsCount, a.CommentsCount+1)
+	}
 }
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func Test_ContentFilters(t *testing.T) {
+	author := articles[2].User
+	authorHeader := tokenHeader(author.Username)
+
+	contentFilters := models.ContentFilters
+	models.ContentFilters = []models.FilterRule{
+		{Filter: models.NewWordFilter([]string{"casino"}), Action: models.FilterReject},
+		{Filter: models.LinkFilter{MaxLinks: 1}, Action: models.FilterFlag},
+		{Filter: models.DuplicateFilter{Window: time.Hour}, Action: models.FilterReject},
+	}
+	defer func() { models.ContentFilters = contentFilters }()
+
+	articleBody := func(body string) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"article": map[string]string{"title": "Filtered Article", "description": "Filtered Article description", "body": body},
+		})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	commentBody := func(body string) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]string{"body": body},
+		})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles", articleBody("Come to my casino"), authorHeader)
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("should reject the article with a banned word: got %v want %v", recorder.Code, http.StatusUnprocessableEntity)
+	}
+
+	var errorResponse errorJSON
+	json.NewDecoder(recorder.Body).Decode(&errorResponse)
+
+	if msgs := errorResponse.Errors["body"]; len(msgs) != 1 || msgs[0] != models.BANNED_WORDS_MSG {
+		t.Errorf("should return the filter errors: got %v", errorResponse.Errors)
+	}
+
+	recorder = makeRequest(t, http.MethodPost, "/api/articles", articleBody("A clean article body"), authorHeader)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("should accept the clean article: got %v want %v", recorder.Code, http.StatusCreated)
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(recorder.Body).Decode(&articleResponse)
+	url := "/api/articles/" + articleResponse.Article.Slug
+
+	if Code := makeRequest(t, http.MethodPut, url, articleBody("Now with a casino"), authorHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject the update with a banned word: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, url+"/comments", commentBody("First!"), authorHeader).Code; Code != http.StatusCreated {
+		t.Fatalf("should accept the first comment: got %v want %v", Code, http.StatusCreated)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, url+"/comments", commentBody(" First! "), authorHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject a recent duplicate: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	recorder = makeRequest(t, http.MethodPost, url+"/comments", commentBody("See https://a.example and https://b.example"), authorHeader)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("should accept the flagged comment: got %v want %v", recorder.Code, http.StatusCreated)
+	}
+
+	var commentResponse CommentJSON
+	json.NewDecoder(recorder.Body).Decode(&commentResponse)
+
+	var reportsResponse ReportsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/moderation/reports", nil, tokenHeader("user5")).Body).Decode(&reportsResponse)
+
+	var flagged []Report
+	for _, report := range reportsResponse.Reports {
+		if report.ArticleSlug == articleResponse.Article.Slug {
+			flagged = append(flagged, report)
+		}
+	}
+
+	if len(flagged) != 1 || flagged[0].Reason != models.FilterReason || flagged[0].Comment == nil || flagged[0].Comment.ID != commentResponse.Comment.ID {
+		t.Fatalf("should queue the flagged comment for moderation: got %v", flagged)
+	}
+
+	if want := "body: " + fmt.Sprintf(models.TOO_MANY_LINKS_MSG, 1); flagged[0].Details != want {
+		t.Errorf("should explain why the comment was flagged: got %v", flagged[0].Details)
+	}
+
+	if Code := postResolution(t, flagged[0].ID, models.ActionDismiss, tokenHeader("user5")).Code; Code != http.StatusOK {
+		t.Fatalf("should dismiss the flag: got %v want %v", Code, http.StatusOK)
+	}
+
+	commentURL := url + "/comments/" + strconv.Itoa(commentResponse.Comment.ID)
+	if Code := makeRequest(t, http.MethodPut, commentURL, commentBody("Also https://c.example and https://d.example"), authorHeader).Code; Code != http.StatusOK {
+		t.Fatalf("should accept the flagged update: got %v want %v", Code, http.StatusOK)
+	}
+
+	var report models.Report
+	DB.Where("reporter_id = 0 AND comment_id = ?", commentResponse.Comment.ID).First(&report)
+
+	if report.Status != models.ReportOpen || report.Action != "" {
+		t.Errorf("should put the content flagged again back in the queue: got %v %v", report.Status, report.Action)
+	}
+}
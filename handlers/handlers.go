@@ -3,6 +3,7 @@ package handlers
 import (
 	"log"
 	"net/http"
+	"net/url"
 
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/auth"
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
@@ -11,9 +12,13 @@ import (
 )
 
 type Handler struct {
-	DB     models.Datastorer
-	JWT    auth.Tokener
-	Logger *log.Logger
+	DB          models.Datastorer
+	JWT         auth.Tokener
+	Logger      *log.Logger
+	Trending    *models.TrendingCache
+	Idempotency IdempotencyStore
+	RateLimiter RateLimitStore
+	Hub         *Hub
 }
 
 type errorJSON struct {
@@ -27,7 +32,10 @@ const (
 )
 
 func New(db *models.DB, jwt *auth.JWT, logger *log.Logger) *Handler {
-	return &Handler{db, jwt, logger}
+	h := &Handler{db, jwt, logger, models.NewTrendingCache(db), NewMemoryIdempotencyStore(), NewMemoryRateLimitStore(), NewHub()}
+	db.Notified = h.publishNotification
+
+	return h
 }
 
 func (h *Handler) authorize() gin.HandlerFunc {
@@ -36,6 +44,10 @@ func (h *Handler) authorize() gin.HandlerFunc {
 			if currentUser, ok := c.Get(currentUserKey); !ok && (currentUser == &models.User{}) {
 				c.Abort()
 				c.String(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			} else if u, _ := currentUser.(*models.User); u != nil && u.IsSuspended() {
+				// The suspended users can still read but can't post anymore
+				c.Abort()
+				c.String(http.StatusForbidden, models.SUSPENDED_MSG)
 			} else {
 				c.Next()
 			}
@@ -47,24 +59,68 @@ func (h *Handler) authorize() gin.HandlerFunc {
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
-	router := gin.Default()
+	router := gin.New()
+	router.Use(h.queryToken(), gin.Logger(), gin.Recovery())
 
 	api := router.Group("/api")
 
 	api.Use(h.getCurrentUser())
+	api.Use(h.rateLimit())
+	api.Use(h.idempotent())
 	api.GET("/articles", h.getArticles)
 	api.POST("/articles", h.authorize(), h.createArticle)
-	api.GET("/articles/:slug", h.extractArticle(), h.getArticle)
+	// The articles can't have the slugs of these routes, see models.ReservedSlugs
+	api.GET("/articles/:slug", articleRoutes(map[string]gin.HandlerFunc{
+		"search":   h.searchArticles,
+		"trending": h.trendingArticles,
+		"feed":     h.getFeed,
+	}), h.extractArticle(), h.getArticle)
 	api.PUT("/articles/:slug", h.authorize(), h.extractArticle(), h.updateArticle)
 	api.DELETE("/articles/:slug", h.authorize(), h.extractArticle(), h.deleteArticle)
 
+	api.GET("/articles/:slug/revisions", h.extractArticle(), h.getRevisions)
+	api.GET("/articles/:slug/revisions/:number", h.extractArticle(), h.getRevision)
+	api.GET("/articles/:slug/revisions/:number/diff", h.extractArticle(), h.diffRevisions)
+	api.POST("/articles/:slug/revisions/:number/restore", h.authorize(), h.extractArticle(), h.restoreRevision)
+
+	api.GET("/articles/:slug/stream", h.extractArticle(), h.streamArticle)
+
 	api.GET("/articles/:slug/comments", h.extractArticle(), h.getComments)
 	api.POST("/articles/:slug/comments", h.authorize(), h.extractArticle(), h.addComment)
 	api.GET("/articles/:slug/comments/:commentID", h.extractArticle(), h.getComment)
+	api.PUT("/articles/:slug/comments/:commentID", h.authorize(), h.extractArticle(), h.updateComment)
+	api.GET("/articles/:slug/comments/:commentID/versions", h.authorize(), h.extractArticle(), h.getCommentVersions)
 	api.DELETE("/articles/:slug/comments/:commentID", h.authorize(), h.extractArticle(), h.deleteComment)
 
+	api.POST("/articles/:slug/report", h.authorize(), h.extractArticle(), h.reportArticle)
+	api.POST("/articles/:slug/comments/:commentID/report", h.authorize(), h.extractArticle(), h.reportComment)
+
 	api.POST("/articles/:slug/favorite", h.authorize(), h.extractArticle(), h.favoriteArticle)
 	api.DELETE("/articles/:slug/favorite", h.authorize(), h.extractArticle(), h.unFavoriteArticle)
+
+	api.GET("/trash", h.authorize(), h.getTrash)
+	api.POST("/trash/articles/:slug/restore", h.authorize(), h.restoreArticle)
+	api.POST("/trash/comments/:commentID/restore", h.authorize(), h.restoreComment)
+
+	api.GET("/moderation/reports", h.authorize(), h.moderate(), h.getReports)
+	api.POST("/moderation/reports/:reportID/claim", h.authorize(), h.moderate(), h.claimReport)
+	api.POST("/moderation/reports/:reportID/resolve", h.authorize(), h.moderate(), h.resolveReport)
+
+	api.GET("/profiles/:username", h.extractProfile(), h.getProfile)
+	api.POST("/profiles/:username/follow", h.authorize(), h.extractProfile(), h.followUser)
+	api.DELETE("/profiles/:username/follow", h.authorize(), h.extractProfile(), h.unfollowUser)
+	api.POST("/profiles/:username/block", h.authorize(), h.extractProfile(), h.blockUser)
+	api.DELETE("/profiles/:username/block", h.authorize(), h.extractProfile(), h.unblockUser)
+	api.POST("/profiles/:username/mute", h.authorize(), h.extractProfile(), h.muteUser)
+	api.DELETE("/profiles/:username/mute", h.authorize(), h.extractProfile(), h.unmuteUser)
+
+	api.GET("/notifications", h.authorize(), h.getNotifications)
+	api.POST("/notifications/read", h.authorize(), h.readNotifications)
+	api.PATCH("/notifications/:notificationID", h.authorize(), h.updateNotification)
+	api.GET("/notifications/preferences", h.authorize(), h.getNotificationPreferences)
+	api.GET("/notifications/stream", h.authorize(), h.streamNotifications)
+	api.PUT("/notifications/preferences", h.authorize(), h.updateNotificationPreferences)
+
 	api.GET("/users", h.currentUser)
 	api.POST("/users", h.registerUser)
 	api.POST("/users/login", h.loginUser)
@@ -76,3 +132,41 @@ func getFromContext(key string, c *gin.Context) interface{} {
 	obj, _ := c.Get(key)
 	return obj
 }
+
+// validateQuery run the given validators against the query string params,
+// it responds with a 422 and returns false when one of them fails.
+func validateQuery(c *gin.Context, validators ...func(url.Values) models.ValidationErrors) bool {
+	var errs = models.ValidationErrors{}
+
+	for _, validate := range validators {
+		for field, messages := range validate(c.Request.Form) {
+			errs[field] = append(errs[field], messages...)
+		}
+	}
+
+	if len(errs) > 0 {
+		errorJSON := errorJSON{errs}
+		c.JSON(http.StatusUnprocessableEntity, errorJSON)
+		return false
+	}
+
+	return true
+}
+
+// extractCursor parse the optional 'cursor' query string param,
+// it responds with a 422 and returns false when the cursor is invalid.
+func extractCursor(c *gin.Context) (*models.Cursor, bool) {
+	token := c.Request.Form.Get("cursor")
+	if token == "" {
+		return nil, true
+	}
+
+	cursor, err := models.ParseCursor(token)
+	if err != nil {
+		errorJSON := errorJSON{models.ValidationErrors{"cursor": []string{err.Error()}}}
+		c.JSON(http.StatusUnprocessableEntity, errorJSON)
+		return nil, false
+	}
+
+	return cursor, true
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// StreamHistory is how many of the last events are kept to resume the streams
+// with the Last-Event-ID header, it can be changed at startup to fit the deployment.
+var StreamHistory = 1000
+
+// subscriptionBuffer is how many events a subscriber can lag behind before being dropped,
+// it is then up to the client to reconnect with the Last-Event-ID header.
+const subscriptionBuffer = 32
+
+// Event is a message published to the subscribers of a topic
+type Event struct {
+	// ID is the position of the event in the hub, the events are numbered from 1
+	ID    int64
+	Topic string
+	Name  string
+	Data  []byte
+	// UserID is the author of the content of the event, 0 when it has none
+	UserID int
+}
+
+// Subscription receive the events published to its topic
+type Subscription struct {
+	// Events is closed when the subscriber is dropped
+	Events <-chan Event
+	topic  string
+	events chan Event
+}
+
+// Hub is an in-process publish/subscribe hub, the events are not shared between instances.
+type Hub struct {
+	mu          sync.Mutex
+	lastID      int64
+	history     []Event
+	subscribers map[string]map[*Subscription]bool
+}
+
+// NewHub initialize a Hub without subscribers
+func NewHub() *Hub {
+	return &Hub{subscribers: map[string]map[*Subscription]bool{}}
+}
+
+// Publish send the event to the subscribers of the topic, data is encoded as JSON.
+// The subscribers lagging too far behind are dropped rather than blocking the publisher.
+func (hub *Hub) Publish(topic string, name string, data interface{}) error {
+	return hub.PublishBy(topic, name, 0, data)
+}
+
+// PublishBy send the event of the content written by the user to the subscribers of the topic,
+// so the subscribers who block or mute the user can skip it. See Publish.
+func (hub *Hub) PublishBy(topic string, name string, userID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.lastID++
+	event := Event{ID: hub.lastID, Topic: topic, Name: name, Data: payload, UserID: userID}
+
+	hub.history = append(hub.history, event)
+	if len(hub.history) > StreamHistory {
+		hub.history = hub.history[len(hub.history)-StreamHistory:]
+	}
+
+	for subscription := range hub.subscribers[topic] {
+		select {
+		case subscription.events <- event:
+		default:
+			hub.drop(subscription)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe to the events of the topic published from now on
+// It also returns the events of the topic published after lastEventID still in the history.
+func (hub *Hub) Subscribe(topic string, lastEventID int64) ([]Event, *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	var missed []Event
+	if lastEventID > 0 {
+		for _, event := range hub.history {
+			if event.ID > lastEventID && event.Topic == topic {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	events := make(chan Event, subscriptionBuffer)
+	subscription := &Subscription{Events: events, topic: topic, events: events}
+
+	if hub.subscribers[topic] == nil {
+		hub.subscribers[topic] = map[*Subscription]bool{}
+	}
+	hub.subscribers[topic][subscription] = true
+
+	return missed, subscription
+}
+
+// Unsubscribe stop sending the events to the subscription
+func (hub *Hub) Unsubscribe(subscription *Subscription) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	hub.drop(subscription)
+}
+
+// drop remove the subscription and close its events, the lock must be held
+func (hub *Hub) drop(subscription *Subscription) {
+	subscribers := hub.subscribers[subscription.topic]
+	if !subscribers[subscription] {
+		return
+	}
+
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(hub.subscribers, subscription.topic)
+	}
+
+	close(subscription.events)
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+// IdempotencyKeyHeader is the header the clients set to retry a mutating request safely,
+// the response of the first request is replayed to the retries with the same key.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayHeader is set on the replayed responses
+const idempotentReplayHeader = "Idempotent-Replayed"
+
+// IdempotencyKeyTTL is how long a response is replayed for its key,
+// it can be changed at startup to fit the deployment.
+var IdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotentResponse is the response stored for an idempotency key
+type IdempotentResponse struct {
+	// Fingerprint identify the request, a key can't be reused for another request
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// IdempotencyStore keep the responses of the requests sent with an idempotency key
+type IdempotencyStore interface {
+	// Reserve the key for the request with the given fingerprint
+	// It returns false when the key is already reserved, along with its response
+	// or nil when the first request is still running.
+	Reserve(key string, fingerprint string) (*IdempotentResponse, bool)
+	// Complete store the response of the request which reserved the key
+	Complete(key string, response *IdempotentResponse)
+	// Release the key so the request can be retried
+	Release(key string)
+}
+
+type idempotencyEntry struct {
+	response  *IdempotentResponse
+	expiresAt time.Time
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore keeping the responses in memory,
+// they are lost on restart and not shared between instances.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]idempotencyEntry
+	expiredAt time.Time
+}
+
+// entriesExpiration is how often the expired entries are deleted from a MemoryIdempotencyStore
+const entriesExpiration = time.Minute
+
+// NewMemoryIdempotencyStore initialize an empty MemoryIdempotencyStore
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{entries: map[string]idempotencyEntry{}}
+}
+
+func (s *MemoryIdempotencyStore) Reserve(key string, fingerprint string) (*IdempotentResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	// The expired entries are only deleted once in a while, they may still be there
+	if entry, ok := s.entries[key]; ok && !now.After(entry.expiresAt) {
+		return entry.response, false
+	}
+
+	s.entries[key] = idempotencyEntry{expiresAt: now.Add(IdempotencyKeyTTL)}
+	return nil, true
+}
+
+func (s *MemoryIdempotencyStore) Complete(key string, response *IdempotentResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = idempotencyEntry{response: response, expiresAt: time.Now().Add(IdempotencyKeyTTL)}
+}
+
+func (s *MemoryIdempotencyStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+}
+
+// expire delete the expired entries, at most once per entriesExpiration, the lock must be held
+func (s *MemoryIdempotencyStore) expire(now time.Time) {
+	if now.Sub(s.expiredAt) < entriesExpiration {
+		return
+	}
+
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	s.expiredAt = now
+}
+
+// idempotent replay the response of a mutating request retried with the same Idempotency-Key header.
+// The keys are scoped to the current user, or to the client address of the anonymous requests,
+// a key reused for another request is rejected and a retry sent while the first request is running gets a 409.
+func (h *Handler) idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.Request.Header.Get(IdempotencyKeyHeader)
+
+		if key == "" || !isMutating(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		body, err := ioutil.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		// The anonymous clients don't share their keys, they would get the responses of the others
+		scope := "ip:" + clientAddress(c.Request)
+		if u := getFromContext(currentUserKey, c).(*models.User); u.ID != 0 {
+			scope = fmt.Sprintf("user:%v", u.ID)
+		}
+		key = scope + ":" + key
+		fingerprint := requestFingerprint(c.Request, body)
+
+		response, reserved := h.Idempotency.Reserve(key, fingerprint)
+
+		if !reserved {
+			c.Abort()
+
+			switch {
+			case response == nil:
+				c.String(http.StatusConflict, models.IDEMPOTENCY_IN_PROGRESS_MSG)
+			case response.Fingerprint != fingerprint:
+				errorJSON := errorJSON{models.ValidationErrors{IdempotencyKeyHeader: []string{models.IDEMPOTENCY_REUSED_MSG}}}
+				c.JSON(http.StatusUnprocessableEntity, errorJSON)
+			default:
+				c.Header(idempotentReplayHeader, "true")
+				c.Data(response.Status, response.ContentType, response.Body)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		// The key is released unless the response is stored, even when the handler panics,
+		// so the request can be retried
+		completed := false
+		defer func() {
+			if !completed {
+				h.Idempotency.Release(key)
+			}
+		}()
+
+		c.Next()
+
+		// The server errors are not stored so the request can be retried
+		if recorder.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		h.Idempotency.Complete(key, &IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      recorder.Status(),
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		completed = true
+	}
+}
+
+// responseRecorder copy the body written to the response
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+func isMutating(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch || method == http.MethodDelete
+}
+
+// requestFingerprint identify a request by its method, URL and body
+func requestFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
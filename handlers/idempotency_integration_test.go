@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+func Test_IdempotentCreateArticle(t *testing.T) {
+	author := articles[0].User
+	other := articles[1].User
+
+	header := func(username string, key string) http.Header {
+		return http.Header{
+			"Authorization":      []string{fmt.Sprintf("Token %v", h.JWT.NewToken(username))},
+			IdempotencyKeyHeader: []string{key},
+		}
+	}
+
+	articleBody := func(title string) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"article": map[string]string{"title": title, "description": title + " description", "body": title + " body"},
+		})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	first := makeRequest(t, http.MethodPost, "/api/articles", articleBody("Idempotent Article"), header(author.Username, "create-1"))
+	retry := makeRequest(t, http.MethodPost, "/api/articles", articleBody("Idempotent Article"), header(author.Username, "create-1"))
+
+	if first.Code != http.StatusCreated || retry.Code != first.Code {
+		t.Fatalf("should replay the status code: got %v want %v", retry.Code, first.Code)
+	}
+
+	if retry.Body.String() != first.Body.String() {
+		t.Errorf("should replay the response body: got %v want %v", retry.Body.String(), first.Body.String())
+	}
+
+	if retry.Header().Get(idempotentReplayHeader) != "true" {
+		t.Errorf("should flag the replayed response")
+	}
+
+	var count int
+	DB.Model(&models.Article{}).Where("title = ?", "Idempotent Article").Count(&count)
+
+	if count != 1 {
+		t.Errorf("should create the article once: got %v want %v", count, 1)
+	}
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles", articleBody("Another Article"), header(author.Username, "create-1"))
+
+	var errorResponse errorJSON
+	json.NewDecoder(recorder.Body).Decode(&errorResponse)
+
+	if _, ok := errorResponse.Errors[IdempotencyKeyHeader]; recorder.Code != http.StatusUnprocessableEntity || !ok {
+		t.Errorf("should not reuse a key for another request: got %v want %v", recorder.Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles", articleBody("Idempotent Article"), header(other.Username, "create-1")).Code; Code != http.StatusCreated {
+		t.Errorf("should scope the keys to their user: got %v want %v", Code, http.StatusCreated)
+	}
+}
+
+func Test_IdempotentAddComment(t *testing.T) {
+	article := articles[1]
+	header := http.Header{
+		"Authorization":      []string{fmt.Sprintf("Token %v", h.JWT.NewToken(article.User.Username))},
+		IdempotencyKeyHeader: []string{"comment-1"},
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Idempotent comment"},
+	})
+
+	var first, retry CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), header).Body).Decode(&first)
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), header).Body).Decode(&retry)
+
+	if first.Comment.ID == 0 || retry.Comment.ID != first.Comment.ID {
+		t.Errorf("should return the comment created by the first request: got %v want %v", retry.Comment.ID, first.Comment.ID)
+	}
+
+	var count int
+	DB.Model(&models.Comment{}).Where("body = ?", "Idempotent comment").Count(&count)
+
+	if count != 1 {
+		t.Errorf("should create the comment once: got %v want %v", count, 1)
+	}
+}
+
+func Test_IdempotentPanic(t *testing.T) {
+	requests := 0
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.POST("/panic", func(c *gin.Context) {
+		c.Set(currentUserKey, &models.User{ID: 1})
+	}, h.idempotent(), func(c *gin.Context) {
+		if requests++; requests == 1 {
+			panic("handler failure")
+		}
+		c.Status(http.StatusCreated)
+	})
+
+	send := func() int {
+		req, _ := http.NewRequest(http.MethodPost, "/panic", bytes.NewBufferString("{}"))
+		req.Header.Set(IdempotencyKeyHeader, "panic-1")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if Code := send(); Code != http.StatusInternalServerError {
+		t.Fatalf("should recover from the panic: got %v want %v", Code, http.StatusInternalServerError)
+	}
+
+	if Code := send(); Code != http.StatusCreated {
+		t.Errorf("should release the key of the request which panicked: got %v want %v", Code, http.StatusCreated)
+	}
+}
+
+func Test_IdempotentAnonymousClients(t *testing.T) {
+	router := h.InitRoutes()
+
+	register := func(remoteAddr string, username string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(map[string]string{"username": username, "email": username + "@example.com", "password": "password"})
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/users", bytes.NewBuffer(jsonBody))
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(IdempotencyKeyHeader, "register-1")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	first := register("192.0.2.1:1234", "idempotent1")
+	if first.Code != http.StatusOK {
+		t.Fatalf("should register the user: got %v want %v", first.Code, http.StatusOK)
+	}
+
+	if retry := register("192.0.2.1:1234", "idempotent1"); retry.Header().Get(idempotentReplayHeader) != "true" || retry.Body.String() != first.Body.String() {
+		t.Errorf("should replay the response to the same client: got %v", retry.Body.String())
+	}
+
+	other := register("192.0.2.2:1234", "idempotent2")
+	if other.Code != http.StatusOK || other.Header().Get(idempotentReplayHeader) != "" {
+		t.Errorf("should not share the keys of the anonymous clients: got %v want %v", other.Code, http.StatusOK)
+	}
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+
+	if _, reserved := store.Reserve("key", "a"); !reserved {
+		t.Fatalf("should reserve a new key")
+	}
+
+	if response, reserved := store.Reserve("key", "a"); reserved || response != nil {
+		t.Errorf("should not reserve a key in progress: got %v want %v", reserved, false)
+	}
+
+	store.Release("key")
+
+	if _, reserved := store.Reserve("key", "a"); !reserved {
+		t.Errorf("should reserve a released key")
+	}
+
+	store.Complete("key", &IdempotentResponse{Fingerprint: "a", Status: http.StatusCreated})
+
+	if response, reserved := store.Reserve("key", "a"); reserved || response == nil || response.Status != http.StatusCreated {
+		t.Errorf("should return the stored response: got %v", response)
+	}
+	// The entry expired but was not deleted yet
+	store.entries["key"] = idempotencyEntry{response: store.entries["key"].response, expiresAt: time.Now().Add(-time.Second)}
+
+	if _, reserved := store.Reserve("key", "a"); !reserved {
+		t.Errorf("should reserve an expired key")
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+type Notification struct {
+	ID           int    `json:"id"`
+	Kind         string `json:"kind"`
+	Read         bool   `json:"read"`
+	Actor        Author `json:"actor"`
+	ArticleSlug  string `json:"articleSlug,omitempty"`
+	ArticleTitle string `json:"articleTitle,omitempty"`
+	CommentID    int    `json:"commentId,omitempty"`
+	CreatedAt    string `json:"createdAt"`
+	ReadAt       string `json:"readAt,omitempty"`
+}
+
+type NotificationJSON struct {
+	Notification `json:"notification"`
+}
+
+type NotificationsJSON struct {
+	Notifications      []Notification `json:"notifications"`
+	NotificationsCount int            `json:"notificationsCount"`
+	UnreadCount        int            `json:"unreadCount"`
+}
+
+type NotificationPreferencesJSON struct {
+	Preferences map[string]bool `json:"preferences"`
+}
+
+type notificationBody struct {
+	Notification struct {
+		Read *bool `json:"read"`
+	} `json:"notification"`
+}
+
+// getNotifications handle GET /api/notifications
+// It lists the notifications of the current user, newest first, along with their counts.
+func (h *Handler) getNotifications(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateNotifications) {
+		return
+	}
+
+	notifications, err := h.DB.GetNotifications(u, c.Request.Form)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	total, unread, err := h.DB.CountNotifications(u)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var notificationsJSON = NotificationsJSON{
+		Notifications:      []Notification{},
+		NotificationsCount: total,
+		UnreadCount:        unread,
+	}
+	for i := range notifications {
+		notificationsJSON.Notifications = append(notificationsJSON.Notifications, h.buildNotificationJSON(&notifications[i], u))
+	}
+
+	c.JSON(http.StatusOK, notificationsJSON)
+}
+
+// updateNotification handle PATCH /api/notifications/:notificationID
+// It marks the notification as read, or unread when the body says so.
+func (h *Handler) updateNotification(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	notificationID, err := strconv.Atoi(c.Param("notificationID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	var notification models.Notification
+	if err := h.DB.GetNotification(u, notificationID, &notification); err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+
+	var notificationBody notificationBody
+	if err := c.BindJSON(&notificationBody); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	read := notificationBody.Notification.Read == nil || *notificationBody.Notification.Read
+
+	if err := h.DB.MarkNotificationRead(&notification, read); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationJSON{h.buildNotificationJSON(&notification, u)})
+}
+
+// readNotifications handle POST /api/notifications/read
+// It marks every notification of the current user as read.
+func (h *Handler) readNotifications(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	if err := h.DB.MarkAllNotificationsRead(u); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.String(http.StatusNoContent, http.StatusText(http.StatusNoContent))
+}
+
+// getNotificationPreferences handle GET /api/notifications/preferences
+func (h *Handler) getNotificationPreferences(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	preferences, err := h.DB.GetNotificationPreferences(u)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, NotificationPreferencesJSON{preferences})
+}
+
+// updateNotificationPreferences handle PUT /api/notifications/preferences
+// The kinds missing from the body are left as they are.
+func (h *Handler) updateNotificationPreferences(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	var body NotificationPreferencesJSON
+	if err := c.BindJSON(&body); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if errs := models.ValidateNotificationPreferences(body.Preferences); errs != nil {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{errs})
+		return
+	}
+
+	if err := h.DB.UpdateNotificationPreferences(u, body.Preferences); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	h.getNotificationPreferences(c)
+}
+
+func (h *Handler) buildNotificationJSON(n *models.Notification, u *models.User) Notification {
+	notification := Notification{
+		ID:   n.ID,
+		Kind: n.Kind,
+		Read: n.IsRead(),
+		Actor: Author{
+			Username:  n.Actor.Username,
+			Bio:       n.Actor.Bio,
+			Image:     n.Actor.Image,
+			Following: h.DB.IsFollowing(u.ID, n.ActorID),
+		},
+		ArticleSlug:  n.Article.Slug,
+		ArticleTitle: n.Article.Title,
+		CommentID:    n.CommentID,
+		CreatedAt:    n.CreatedAt.Format(time.RFC3339),
+	}
+
+	if n.ReadAt != nil {
+		notification.ReadAt = n.ReadAt.Format(time.RFC3339)
+	}
+
+	return notification
+}
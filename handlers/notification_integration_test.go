@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func getNotifications(t *testing.T, url string, header http.Header) NotificationsJSON {
+	var notificationsResponse NotificationsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, url, nil, header).Body).Decode(&notificationsResponse)
+	return notificationsResponse
+}
+
+func Test_Notifications(t *testing.T) {
+	a := articles[3]
+	author := a.User
+	authorHeader := tokenHeader(author.Username)
+	actorHeader := tokenHeader("user6")
+
+	DB.Where("user_id = ?", author.ID).Delete(models.Notification{})
+
+	comment := func(header http.Header) string {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]string{"body": "Notified comment"},
+		})
+		recorder := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", bytes.NewBuffer(jsonBody), header)
+
+		var commentResponse CommentJSON
+		json.NewDecoder(recorder.Body).Decode(&commentResponse)
+		return "/api/articles/" + a.Slug + "/comments/" + strconv.Itoa(commentResponse.Comment.ID)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/notifications", nil, nil).Code; Code != http.StatusUnauthorized {
+		t.Errorf("should only list the notifications of a user: got %v want %v", Code, http.StatusUnauthorized)
+	}
+
+	defer makeRequest(t, http.MethodDelete, comment(actorHeader), nil, actorHeader)
+	defer makeRequest(t, http.MethodDelete, comment(authorHeader), nil, authorHeader)
+	makeRequest(t, http.MethodPost, "/api/profiles/"+author.Username+"/follow", nil, actorHeader)
+	defer makeRequest(t, http.MethodDelete, "/api/profiles/"+author.Username+"/follow", nil, actorHeader)
+	makeRequest(t, http.MethodDelete, "/api/articles/"+a.Slug+"/favorite", nil, actorHeader)
+	makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/favorite", nil, actorHeader)
+	makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/favorite", nil, actorHeader)
+
+	notificationsResponse := getNotifications(t, "/api/notifications", authorHeader)
+
+	if notificationsResponse.NotificationsCount != 3 || notificationsResponse.UnreadCount != 3 || len(notificationsResponse.Notifications) != 3 {
+		t.Fatalf("should notify the comment, the follow and the favorite once: got %v", notificationsResponse)
+	}
+
+	var kinds []string
+	for _, notification := range notificationsResponse.Notifications {
+		kinds = append(kinds, notification.Kind)
+	}
+
+	if kinds[0] != models.NotifyFavorite || kinds[1] != models.NotifyFollow || kinds[2] != models.NotifyComment {
+		t.Errorf("should list the newest notifications first: got %v", kinds)
+	}
+
+	if first := notificationsResponse.Notifications[0]; first.Actor.Username != "user6" || first.ArticleSlug != a.Slug || first.Read {
+		t.Errorf("should return the unread notification with its actor and article: got %v", first)
+	}
+
+	url := "/api/notifications/" + strconv.Itoa(notificationsResponse.Notifications[2].ID)
+
+	if Code := makeRequest(t, http.MethodPatch, url, bytes.NewBufferString("{}"), actorHeader).Code; Code != http.StatusNotFound {
+		t.Errorf("should not let another user read the notification: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	var notificationResponse NotificationJSON
+	json.NewDecoder(makeRequest(t, http.MethodPatch, url, bytes.NewBufferString("{}"), authorHeader).Body).Decode(&notificationResponse)
+
+	if !notificationResponse.Notification.Read || notificationResponse.Notification.ReadAt == "" {
+		t.Errorf("should mark the notification as read: got %v", notificationResponse.Notification)
+	}
+
+	notificationsResponse = getNotifications(t, "/api/notifications?unread=true", authorHeader)
+
+	if notificationsResponse.UnreadCount != 2 || len(notificationsResponse.Notifications) != 2 {
+		t.Errorf("should only list the unread notifications: got %v", notificationsResponse)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/notifications?unread=maybe", nil, authorHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should validate the unread param: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/notifications/read", nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Errorf("should mark every notification as read: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	if unread := getNotifications(t, "/api/notifications", authorHeader).UnreadCount; unread != 0 {
+		t.Errorf("should have no unread notification left: got %v", unread)
+	}
+
+	preferencesBody := func(preferences map[string]bool) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(NotificationPreferencesJSON{preferences})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	if Code := makeRequest(t, http.MethodPut, "/api/notifications/preferences", preferencesBody(map[string]bool{"mention": false}), authorHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject an unknown kind: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	var preferencesResponse NotificationPreferencesJSON
+	json.NewDecoder(makeRequest(t, http.MethodPut, "/api/notifications/preferences", preferencesBody(map[string]bool{models.NotifyComment: false}), authorHeader).Body).Decode(&preferencesResponse)
+	defer makeRequest(t, http.MethodPut, "/api/notifications/preferences", preferencesBody(map[string]bool{models.NotifyComment: true}), authorHeader)
+
+	if preferencesResponse.Preferences[models.NotifyComment] || !preferencesResponse.Preferences[models.NotifyFollow] {
+		t.Errorf("should only turn the comments off: got %v", preferencesResponse.Preferences)
+	}
+
+	defer makeRequest(t, http.MethodDelete, comment(actorHeader), nil, actorHeader)
+
+	if total := getNotifications(t, "/api/notifications", authorHeader).NotificationsCount; total != 3 {
+		t.Errorf("should not notify the kinds turned off: got %v want %v", total, 3)
+	}
+
+	makeRequest(t, http.MethodPost, "/api/profiles/user6/mute", nil, authorHeader)
+	defer makeRequest(t, http.MethodDelete, "/api/profiles/user6/mute", nil, authorHeader)
+	makeRequest(t, http.MethodDelete, "/api/articles/"+a.Slug+"/favorite", nil, actorHeader)
+	makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/favorite", nil, actorHeader)
+
+	if total := getNotifications(t, "/api/notifications", authorHeader).NotificationsCount; total != 3 {
+		t.Errorf("should not notify the actions of the muted users: got %v want %v", total, 3)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+type Profile struct {
+	Username  string `json:"username"`
+	Bio       string `json:"bio"`
+	Image     string `json:"image"`
+	Following bool   `json:"following"`
+	Blocking  bool   `json:"blocking"`
+	Muting    bool   `json:"muting"`
+}
+
+type ProfileJSON struct {
+	Profile `json:"profile"`
+}
+
+const fetchedProfileKey = "profile"
+
+// extractProfile fetch the user with the :username param, it writes a 404 when there is none
+func (h *Handler) extractProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profile, err := h.DB.FindUserByUsername(c.Param("username"))
+		if err != nil {
+			c.Abort()
+			c.String(http.StatusNotFound, err.Error())
+			return
+		}
+
+		c.Set(fetchedProfileKey, profile)
+		c.Next()
+	}
+}
+
+// getProfile handle GET /api/profiles/:username
+func (h *Handler) getProfile(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+	profile := getFromContext(fetchedProfileKey, c).(*models.User)
+
+	c.JSON(http.StatusOK, ProfileJSON{h.buildProfileJSON(profile, u)})
+}
+
+// followUser handle POST /api/profiles/:username/follow
+// The users blocked by the profile can't follow it.
+func (h *Handler) followUser(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+	profile := getFromContext(fetchedProfileKey, c).(*models.User)
+
+	if h.DB.IsBlocking(profile.ID, u.ID) {
+		c.String(http.StatusForbidden, models.BLOCKED_MSG)
+		return
+	}
+
+	h.updateProfile(c, "follow", h.DB.FollowUser)
+}
+
+// unfollowUser handle DELETE /api/profiles/:username/follow
+func (h *Handler) unfollowUser(c *gin.Context) {
+	h.updateProfile(c, "unfollow", h.DB.UnfollowUser)
+}
+
+// blockUser handle POST /api/profiles/:username/block
+func (h *Handler) blockUser(c *gin.Context) {
+	h.updateProfile(c, "block", h.DB.BlockUser)
+}
+
+// unblockUser handle DELETE /api/profiles/:username/block
+func (h *Handler) unblockUser(c *gin.Context) {
+	h.updateProfile(c, "unblock", h.DB.UnblockUser)
+}
+
+// muteUser handle POST /api/profiles/:username/mute
+// The muted user is not told anything, only the current user stops seeing their content.
+func (h *Handler) muteUser(c *gin.Context) {
+	h.updateProfile(c, "mute", h.DB.MuteUser)
+}
+
+// unmuteUser handle DELETE /api/profiles/:username/mute
+func (h *Handler) unmuteUser(c *gin.Context) {
+	h.updateProfile(c, "unmute", h.DB.UnmuteUser)
+}
+
+// updateProfile apply the action of the current user to the fetched profile and respond with the profile
+func (h *Handler) updateProfile(c *gin.Context, verb string, action func(*models.User, *models.User) error) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+	profile := getFromContext(fetchedProfileKey, c).(*models.User)
+
+	if profile.ID == u.ID {
+		errorJSON := errorJSON{models.ValidationErrors{"username": []string{fmt.Sprintf(models.SELF_MSG, verb)}}}
+		c.JSON(http.StatusUnprocessableEntity, errorJSON)
+		return
+	}
+
+	if err := action(u, profile); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ProfileJSON{h.buildProfileJSON(profile, u)})
+}
+
+// hiddenUsers returns the users whose content is hidden from the given user, they block or mute them
+func (h *Handler) hiddenUsers(u *models.User) map[int]bool {
+	hidden := map[int]bool{}
+	if u.ID == 0 {
+		return hidden
+	}
+
+	ids, err := h.DB.GetHiddenUserIDs(u)
+	if err != nil {
+		h.Logger.Println("hidden users:", err)
+	}
+
+	for _, id := range ids {
+		hidden[id] = true
+	}
+
+	return hidden
+}
+
+func (h *Handler) buildProfileJSON(profile *models.User, u *models.User) Profile {
+	p := Profile{
+		Username: profile.Username,
+		Bio:      profile.Bio,
+		Image:    profile.Image,
+	}
+
+	// Only the user knows who they block or mute
+	if u.ID != 0 {
+		p.Following = h.DB.IsFollowing(u.ID, profile.ID)
+		p.Blocking = h.DB.IsBlocking(u.ID, profile.ID)
+		p.Muting = h.DB.IsMuting(u.ID, profile.ID)
+	}
+
+	return p
+}
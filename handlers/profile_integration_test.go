@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func Test_GetProfile(t *testing.T) {
+	if Code := makeRequest(t, http.MethodGet, "/api/profiles/nobody", nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should return a 404 for an unknown user: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	recorder := makeRequest(t, http.MethodGet, "/api/profiles/user1", nil, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", recorder.Code, http.StatusOK)
+	}
+
+	var profileResponse ProfileJSON
+	json.NewDecoder(recorder.Body).Decode(&profileResponse)
+
+	if profileResponse.Profile.Username != "user1" || profileResponse.Profile.Following {
+		t.Errorf("should return the profile: got %v", profileResponse.Profile)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/profiles/user1/follow", nil, tokenHeader("user1")).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not let a user follow themselves: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
+
+func Test_FollowAndBlock(t *testing.T) {
+	a := articles[1]
+	blocker := a.User
+	blockerHeader := tokenHeader(blocker.Username)
+	targetHeader := tokenHeader("user7")
+
+	target, _ := h.DB.FindUserByUsername("user7")
+	targetArticle := models.NewArticle("Blocked Article", "Blocked Article description", "Blocked Article body", target)
+	if err := h.DB.CreateArticle(targetArticle); err != nil {
+		t.Fatal(err)
+	}
+
+	profileURL := func(username string, action string) string {
+		return "/api/profiles/" + username + "/" + action
+	}
+
+	listed := func(url string, header http.Header, slug string) bool {
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(makeRequest(t, http.MethodGet, url, nil, header).Body).Decode(&articlesResponse)
+
+		for _, article := range articlesResponse.Articles {
+			if article.Slug == slug {
+				return true
+			}
+		}
+		return false
+	}
+
+	commentBody := func(body string) *bytes.Buffer {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]string{"body": body},
+		})
+		return bytes.NewBuffer(jsonBody)
+	}
+
+	var profileResponse ProfileJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, profileURL(target.Username, "follow"), nil, blockerHeader).Body).Decode(&profileResponse)
+
+	if !profileResponse.Profile.Following {
+		t.Errorf("should follow the user: got %v", profileResponse.Profile)
+	}
+
+	makeRequest(t, http.MethodPost, profileURL(blocker.Username, "follow"), nil, targetHeader)
+
+	if !listed("/api/articles/feed?limit=100", blockerHeader, targetArticle.Slug) {
+		t.Errorf("should list the articles of the followed users in the feed")
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/feed", nil, nil).Code; Code != http.StatusUnauthorized {
+		t.Errorf("should only return the feed of a user: got %v want %v", Code, http.StatusUnauthorized)
+	}
+
+	profileResponse = ProfileJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodPost, profileURL(target.Username, "block"), nil, blockerHeader).Body).Decode(&profileResponse)
+
+	if !profileResponse.Profile.Blocking || profileResponse.Profile.Following || h.DB.IsFollowing(target.ID, blocker.ID) {
+		t.Errorf("should block the user and remove the follows: got %v", profileResponse.Profile)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, profileURL(blocker.Username, "follow"), nil, targetHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should not let a blocked user follow: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", commentBody("Blocked"), targetHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should not let a blocked user comment: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/favorite", nil, targetHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should not let a blocked user favorite: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	if listed("/api/articles?limit=100", blockerHeader, targetArticle.Slug) {
+		t.Errorf("should exclude the articles of the blocked users from the listings")
+	}
+
+	if !listed("/api/articles?limit=100", nil, targetArticle.Slug) {
+		t.Errorf("should only exclude the articles for the blocker")
+	}
+
+	makeRequest(t, http.MethodDelete, profileURL(target.Username, "block"), nil, blockerHeader)
+	profileResponse = ProfileJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodPost, profileURL(target.Username, "mute"), nil, blockerHeader).Body).Decode(&profileResponse)
+
+	if profileResponse.Profile.Blocking || !profileResponse.Profile.Muting {
+		t.Errorf("should unblock then mute the user: got %v", profileResponse.Profile)
+	}
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", commentBody("Muted"), targetHeader)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("should let a muted user comment: got %v want %v", recorder.Code, http.StatusCreated)
+	}
+
+	var commentResponse CommentJSON
+	json.NewDecoder(recorder.Body).Decode(&commentResponse)
+	commentURL := "/api/articles/" + a.Slug + "/comments/" + strconv.Itoa(commentResponse.Comment.ID)
+	defer makeRequest(t, http.MethodDelete, commentURL, nil, targetHeader)
+
+	profileResponse = ProfileJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/profiles/"+blocker.Username, nil, targetHeader).Body).Decode(&profileResponse)
+
+	if profileResponse.Profile.Muting || profileResponse.Profile.Blocking {
+		t.Errorf("should not tell the muted user: got %v", profileResponse.Profile)
+	}
+
+	commentListed := func(header http.Header) bool {
+		var commentsResponse CommentsJSON
+		json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug+"/comments", nil, header).Body).Decode(&commentsResponse)
+
+		for _, comment := range commentsResponse.Comments {
+			if comment.ID == commentResponse.Comment.ID {
+				return true
+			}
+		}
+		return false
+	}
+
+	if commentListed(blockerHeader) || !commentListed(nil) {
+		t.Errorf("should only exclude the comments of the muted users for the user muting them")
+	}
+
+	if listed("/api/articles?limit=100", blockerHeader, targetArticle.Slug) {
+		t.Errorf("should exclude the articles of the muted users from the listings")
+	}
+
+	makeRequest(t, http.MethodDelete, profileURL(target.Username, "mute"), nil, blockerHeader)
+
+	if h.DB.IsMuting(blocker.ID, target.ID) || !commentListed(blockerHeader) {
+		t.Errorf("should unmute the user")
+	}
+}
+
+func Test_FeedCursor(t *testing.T) {
+	readerHeader := tokenHeader("user6")
+
+	for _, a := range []*models.Article{articles[0], articles[2]} {
+		makeRequest(t, http.MethodPost, "/api/profiles/"+a.User.Username+"/follow", nil, readerHeader)
+		defer makeRequest(t, http.MethodDelete, "/api/profiles/"+a.User.Username+"/follow", nil, readerHeader)
+	}
+
+	var feedResponse ArticlesJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/feed?limit=100&sort=oldest", nil, readerHeader).Body).Decode(&feedResponse)
+
+	if len(feedResponse.Articles) < 2 {
+		t.Fatalf("should list the articles of the followed users: got %v", len(feedResponse.Articles))
+	}
+
+	var slugs []string
+	var cursor string
+
+	for page := 0; page < len(feedResponse.Articles); page++ {
+		recorder := makeRequest(t, http.MethodGet, "/api/articles/feed?limit=1&sort=oldest&cursor="+cursor, nil, readerHeader)
+
+		if Code := recorder.Code; Code != http.StatusOK {
+			t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+		}
+
+		var articlesResponse ArticlesJSON
+		json.NewDecoder(recorder.Body).Decode(&articlesResponse)
+
+		for _, article := range articlesResponse.Articles {
+			slugs = append(slugs, article.Slug)
+		}
+
+		if cursor = articlesResponse.NextCursor; cursor == "" {
+			break
+		}
+	}
+
+	if len(slugs) != len(feedResponse.Articles) {
+		t.Fatalf("should page through the whole feed: got %v want %v", len(slugs), len(feedResponse.Articles))
+	}
+
+	for i, slug := range slugs {
+		if expected := feedResponse.Articles[i].Slug; slug != expected {
+			t.Errorf("should return the feed in the same order: got %v want %v", slug, expected)
+		}
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/feed?cursor=invalid", nil, readerHeader).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should validate the cursor: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+}
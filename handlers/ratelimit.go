@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+// Route groups sharing a rate limit budget
+const (
+	AuthRoutes  = "auth"
+	WriteRoutes = "writes"
+	ReadRoutes  = "reads"
+)
+
+// RateLimit is the budget of a route group: a client can send Requests requests at once,
+// then its budget refills at the pace of Requests per Period.
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// RateLimits are the budgets of the route groups, a group without budget is not limited.
+// They can be changed at startup to fit the deployment.
+var RateLimits = map[string]RateLimit{
+	AuthRoutes:  {Requests: 10, Period: time.Minute},
+	WriteRoutes: {Requests: 60, Period: time.Minute},
+	ReadRoutes:  {Requests: 300, Period: time.Minute},
+}
+
+// TrustedProxies are the addresses of the reverse proxies in front of the API, the client
+// address is only read from the X-Real-Ip and X-Forwarded-For headers they set.
+// It can be changed at startup to fit the deployment.
+var TrustedProxies []string
+
+// authRoutes are the routes limited by the AuthRoutes budget, keyed by method and path
+var authRoutes = map[string]bool{
+	"POST /api/users":       true,
+	"POST /api/users/login": true,
+}
+
+// RateLimitStatus is the state of a budget after a request took from it
+type RateLimitStatus struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is when the next request will be allowed, 0 when it already is
+	RetryAfter time.Duration
+	// Reset is when the budget will be full again
+	Reset time.Duration
+}
+
+// RateLimitStore keep the token buckets of the clients
+type RateLimitStore interface {
+	// Take a token from the bucket of the key, the request is not allowed when it is empty
+	Take(key string, limit RateLimit) RateLimitStatus
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	// fullAt is when the bucket is full again, it is the same as a new one from then on
+	fullAt time.Time
+}
+
+// bucketsExpiration is how often the full buckets are deleted from a MemoryRateLimitStore
+const bucketsExpiration = time.Minute
+
+// MemoryRateLimitStore is a RateLimitStore keeping the buckets in memory,
+// they are lost on restart and not shared between instances.
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	expiredAt time.Time
+}
+
+// NewMemoryRateLimitStore initialize an empty MemoryRateLimitStore
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]*tokenBucket{}}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) RateLimitStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.expire(now)
+
+	capacity := float64(limit.Requests)
+	perToken := limit.Period / time.Duration(limit.Requests)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(capacity, bucket.tokens+float64(now.Sub(bucket.updatedAt))/float64(perToken))
+	bucket.updatedAt = now
+
+	status := RateLimitStatus{Allowed: bucket.tokens >= 1}
+	if status.Allowed {
+		bucket.tokens--
+	} else {
+		status.RetryAfter = time.Duration((1 - bucket.tokens) * float64(perToken))
+	}
+
+	status.Remaining = int(bucket.tokens)
+	status.Reset = time.Duration((capacity - bucket.tokens) * float64(perToken))
+	bucket.fullAt = now.Add(status.Reset)
+
+	return status
+}
+
+// expire delete the full buckets, at most once per bucketsExpiration, the lock must be held
+func (s *MemoryRateLimitStore) expire(now time.Time) {
+	if now.Sub(s.expiredAt) < bucketsExpiration {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if now.After(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.expiredAt = now
+}
+
+// rateLimit limit the requests of the clients with the budget of the route group,
+// the clients are the current user or the client IP for the anonymous requests.
+// It must follow getCurrentUser.
+func (h *Handler) rateLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group := rateLimitGroup(c.Request)
+
+		limit, ok := RateLimits[group]
+		if !ok || limit.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		client := "ip:" + clientAddress(c.Request)
+		if u, _ := getFromContext(currentUserKey, c).(*models.User); u != nil && u.ID != 0 {
+			client = fmt.Sprintf("user:%v", u.ID)
+		}
+
+		status := h.RateLimiter.Take(group+":"+client, limit)
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(status.Reset)))
+
+		if !status.Allowed {
+			c.Abort()
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(status.RetryAfter)))
+			c.String(http.StatusTooManyRequests, http.StatusText(http.StatusTooManyRequests))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitGroup returns the route group of the request
+func rateLimitGroup(r *http.Request) string {
+	switch {
+	case authRoutes[r.Method+" "+r.URL.Path]:
+		return AuthRoutes
+	case isMutating(r.Method):
+		return WriteRoutes
+	default:
+		return ReadRoutes
+	}
+}
+
+// clientAddress returns the address the request comes from, the forwarded headers are
+// ignored unless it comes through one of the TrustedProxies as any client can set them.
+func clientAddress(r *http.Request) string {
+	address, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		address = r.RemoteAddr
+	}
+
+	trusted := false
+	for _, proxy := range TrustedProxies {
+		trusted = trusted || proxy == address
+	}
+
+	if !trusted {
+		return address
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-Ip")); realIP != "" {
+		return realIP
+	}
+
+	// The proxy appends the address it received the request from, the first ones are the client's claims
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	if last := strings.TrimSpace(forwarded[len(forwarded)-1]); last != "" {
+		return last
+	}
+
+	return address
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_RateLimit(t *testing.T) {
+	rateLimits, rateLimiter := RateLimits, h.RateLimiter
+	RateLimits = map[string]RateLimit{
+		AuthRoutes:  {Requests: 1, Period: time.Minute},
+		WriteRoutes: {Requests: 2, Period: time.Minute},
+		ReadRoutes:  {Requests: 3, Period: time.Minute},
+	}
+	h.RateLimiter = NewMemoryRateLimitStore()
+	defer func() { RateLimits, h.RateLimiter = rateLimits, rateLimiter }()
+
+	url := "/api/articles/" + articles[0].Slug
+
+	for i := 3; i > 0; i-- {
+		recorder := makeRequest(t, http.MethodGet, url, nil, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("should allow the requests within the budget: got %v want %v", recorder.Code, http.StatusOK)
+		}
+
+		if limit := recorder.Header().Get("X-RateLimit-Limit"); limit != "3" {
+			t.Errorf("should return the budget: got %v want %v", limit, 3)
+		}
+
+		if remaining := recorder.Header().Get("X-RateLimit-Remaining"); remaining != strconv.Itoa(i-1) {
+			t.Errorf("should return the remaining requests: got %v want %v", remaining, i-1)
+		}
+	}
+
+	recorder := makeRequest(t, http.MethodGet, url, nil, nil)
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("should limit the requests over the budget: got %v want %v", recorder.Code, http.StatusTooManyRequests)
+	}
+
+	// A request is allowed again every 20 seconds
+	if retryAfter := recorder.Header().Get("Retry-After"); retryAfter != "20" {
+		t.Errorf("should return when to retry: got %v want %v", retryAfter, 20)
+	}
+
+	if reset := recorder.Header().Get("X-RateLimit-Reset"); reset != "60" {
+		t.Errorf("should return when the budget is full again: got %v want %v", reset, 60)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, url, nil, tokenHeader("user6")).Code; Code != http.StatusOK {
+		t.Errorf("should limit the users apart from the anonymous clients: got %v want %v", Code, http.StatusOK)
+	}
+
+	for i := 0; i < 2; i++ {
+		makeRequest(t, http.MethodPost, url+"/favorite", nil, tokenHeader("user6"))
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, url+"/favorite", nil, tokenHeader("user6")).Code; Code != http.StatusTooManyRequests {
+		t.Errorf("should limit the writes with their own budget: got %v want %v", Code, http.StatusTooManyRequests)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, url+"/favorite", nil, tokenHeader("user7")).Code; Code != http.StatusOK {
+		t.Errorf("should give each user their own budget: got %v want %v", Code, http.StatusOK)
+	}
+
+	login := func() int {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"user": map[string]string{"email": "nobody@example.com", "password": "wrong"},
+		})
+		return makeRequest(t, http.MethodPost, "/api/users/login", bytes.NewBuffer(jsonBody), nil).Code
+	}
+
+	if Code := login(); Code == http.StatusTooManyRequests {
+		t.Errorf("should allow the first login attempt: got %v", Code)
+	}
+
+	if Code := login(); Code != http.StatusTooManyRequests {
+		t.Errorf("should limit the login attempts: got %v want %v", Code, http.StatusTooManyRequests)
+	}
+}
+
+func Test_RateLimitForwardedHeaders(t *testing.T) {
+	rateLimits, rateLimiter, trustedProxies := RateLimits, h.RateLimiter, TrustedProxies
+	RateLimits = map[string]RateLimit{AuthRoutes: {Requests: 1, Period: time.Minute}}
+	h.RateLimiter = NewMemoryRateLimitStore()
+	defer func() { RateLimits, h.RateLimiter, TrustedProxies = rateLimits, rateLimiter, trustedProxies }()
+
+	router := h.InitRoutes()
+
+	login := func(remoteAddr string, forwardedFor string) int {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"user": map[string]string{"email": "nobody@example.com", "password": "wrong"},
+		})
+
+		req, err := http.NewRequest(http.MethodPost, "/api/users/login", bytes.NewBuffer(jsonBody))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Real-Ip", forwardedFor)
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	if Code := login("192.0.2.1:1234", "198.51.100.1"); Code == http.StatusTooManyRequests {
+		t.Errorf("should allow the first login attempt: got %v", Code)
+	}
+
+	if Code := login("192.0.2.1:1234", "198.51.100.2"); Code != http.StatusTooManyRequests {
+		t.Errorf("should not trust the forwarded headers of a client: got %v want %v", Code, http.StatusTooManyRequests)
+	}
+
+	TrustedProxies = []string{"192.0.2.10"}
+
+	if Code := login("192.0.2.10:1234", "198.51.100.3"); Code == http.StatusTooManyRequests {
+		t.Errorf("should limit the clients behind a trusted proxy apart: got %v", Code)
+	}
+
+	if Code := login("192.0.2.10:1234", "198.51.100.3"); Code != http.StatusTooManyRequests {
+		t.Errorf("should limit a client behind a trusted proxy: got %v want %v", Code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,289 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+type Report struct {
+	ID          int      `json:"id"`
+	Reason      string   `json:"reason"`
+	Details     string   `json:"details"`
+	Status      string   `json:"status"`
+	Action      string   `json:"action,omitempty"`
+	ArticleSlug string   `json:"articleSlug"`
+	Article     *Article `json:"article,omitempty"`
+	Comment     *Comment `json:"comment,omitempty"`
+	Reporter    Author   `json:"reporter"`
+	Moderator   *Author  `json:"moderator,omitempty"`
+	CreatedAt   string   `json:"createdAt"`
+	ClaimedAt   string   `json:"claimedAt,omitempty"`
+	ResolvedAt  string   `json:"resolvedAt,omitempty"`
+}
+
+type ReportJSON struct {
+	Report `json:"report"`
+}
+
+type ReportsJSON struct {
+	Reports []Report `json:"reports"`
+}
+
+type reportBody struct {
+	Report struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	} `json:"report"`
+}
+
+type resolutionBody struct {
+	Resolution struct {
+		Action string `json:"action"`
+	} `json:"resolution"`
+}
+
+// moderate only let the moderators through, it must follow authorize
+func (h *Handler) moderate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if u := getFromContext(currentUserKey, c).(*models.User); !u.IsModerator() {
+			c.Abort()
+			c.String(http.StatusForbidden, http.StatusText(http.StatusForbidden))
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// reportArticle handle POST /api/articles/:slug/report
+func (h *Handler) reportArticle(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+	h.addReport(c, a, nil)
+}
+
+// reportComment handle POST /api/articles/:slug/comments/:commentID/report
+func (h *Handler) reportComment(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+
+	comment, ok := h.extractComment(c)
+	if !ok {
+		return
+	}
+
+	h.addReport(c, a, comment)
+}
+
+// getReports handle GET /api/moderation/reports
+// It lists the reports with the given status (default: open), oldest first.
+func (h *Handler) getReports(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	c.Request.ParseForm()
+
+	if !validateQuery(c, models.ValidatePagination, models.ValidateReports) {
+		return
+	}
+
+	reports, err := h.DB.GetReports(c.Request.Form)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var reportsJSON = ReportsJSON{Reports: []Report{}}
+	for i := range reports {
+		reportsJSON.Reports = append(reportsJSON.Reports, h.buildReportJSON(&reports[i], u))
+	}
+
+	c.JSON(http.StatusOK, reportsJSON)
+}
+
+// claimReport handle POST /api/moderation/reports/:reportID/claim
+func (h *Handler) claimReport(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	report, ok := h.extractReport(c)
+	if !ok {
+		return
+	}
+
+	if !report.CanBeClaimedBy(u) {
+		c.String(http.StatusConflict, http.StatusText(http.StatusConflict))
+		return
+	}
+
+	if err := h.DB.ClaimReport(report, u); err != nil {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, ReportJSON{h.buildReportJSON(report, u)})
+}
+
+// resolveReport handle POST /api/moderation/reports/:reportID/resolve
+// The action applies to the reported content and resolves all its reports.
+func (h *Handler) resolveReport(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	report, ok := h.extractReport(c)
+	if !ok {
+		return
+	}
+
+	if !report.CanBeResolvedBy(u) {
+		c.String(http.StatusConflict, http.StatusText(http.StatusConflict))
+		return
+	}
+
+	var resolutionBody resolutionBody
+	if err := c.BindJSON(&resolutionBody); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if errs := models.ValidateModerationAction(resolutionBody.Resolution.Action); errs != nil {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{errs})
+		return
+	}
+
+	if err := h.DB.ResolveReport(report, u, resolutionBody.Resolution.Action); err != nil {
+		c.String(http.StatusConflict, err.Error())
+		return
+	}
+
+	// The comments flagged by the content filters, reported with no reporter,
+	// are only pushed to the stream once a moderator clears them
+	comment := &report.Comment
+	if resolutionBody.Resolution.Action == models.ActionDismiss && comment.ID != 0 && h.DB.HasReported(&models.User{}, &report.Article, comment) {
+		h.publishComment(&report.Article, commentCreatedEvent, comment.UserID, h.buildCommentJSON(comment, &models.User{}))
+	}
+
+	c.JSON(http.StatusOK, ReportJSON{h.buildReportJSON(report, u)})
+}
+
+func (h *Handler) addReport(c *gin.Context, a *models.Article, comment *models.Comment) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	var reportBody reportBody
+	if err := c.BindJSON(&reportBody); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	report, errs := models.NewReport(u, a, comment, reportBody.Report.Reason, reportBody.Report.Details)
+
+	if errs == nil && h.DB.HasReported(u, a, comment) {
+		errs = models.ValidationErrors{"report": []string{models.ALREADY_REPORTED_MSG}}
+	}
+
+	if errs != nil {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{errs})
+		return
+	}
+
+	if err := h.DB.CreateReport(report); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	if err := h.DB.GetReport(report.ID, report); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, ReportJSON{h.buildReportJSON(report, u)})
+}
+
+// filterContent run the content through the content filters
+// It writes a 422 response and returns false when a filter rejects the content,
+// otherwise it returns the errors of the filters flagging it, nil when none did.
+func (h *Handler) filterContent(c *gin.Context, content *models.Content) (models.ValidationErrors, bool) {
+	rejected, flagged := h.DB.FilterContent(content)
+
+	if rejected != nil {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{rejected})
+		return nil, false
+	}
+
+	return flagged, true
+}
+
+// flagContent report the saved content flagged by the filters to the moderators,
+// a failure is only logged since the content is saved already.
+func (h *Handler) flagContent(a *models.Article, comment *models.Comment, flagged models.ValidationErrors) {
+	if flagged == nil {
+		return
+	}
+
+	if err := h.DB.FlagContent(a, comment, flagged); err != nil {
+		h.Logger.Println("content filters:", err)
+	}
+}
+
+// extractReport get the report with the :reportID param,
+// it writes a 400 or a 404 response and returns false when there is none.
+func (h *Handler) extractReport(c *gin.Context) (*models.Report, bool) {
+	reportID, err := strconv.Atoi(c.Param("reportID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return nil, false
+	}
+
+	var report models.Report
+	if err := h.DB.GetReport(reportID, &report); err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return &report, true
+}
+
+func (h *Handler) buildReportJSON(r *models.Report, u *models.User) Report {
+	report := Report{
+		ID:          r.ID,
+		Reason:      r.Reason,
+		Details:     r.Details,
+		Status:      r.Status,
+		Action:      r.Action,
+		ArticleSlug: r.Article.Slug,
+		Reporter: Author{
+			Username: r.Reporter.Username,
+			Bio:      r.Reporter.Bio,
+			Image:    r.Reporter.Image,
+		},
+		CreatedAt: r.CreatedAt.Format(time.RFC3339),
+	}
+
+	// Only the moderators review the reported content
+	if u.IsModerator() {
+		if r.IsComment() && r.Comment.ID != 0 {
+			comment := h.buildCommentJSON(&r.Comment, u)
+			report.Comment = &comment
+		} else if !r.IsComment() && r.Article.ID != 0 {
+			article := h.buildArticleJSON(&r.Article, u)
+			report.Article = &article
+		}
+	}
+
+	if r.ModeratorID != 0 {
+		report.Moderator = &Author{
+			Username: r.Moderator.Username,
+			Bio:      r.Moderator.Bio,
+			Image:    r.Moderator.Image,
+		}
+	}
+
+	if r.ClaimedAt != nil {
+		report.ClaimedAt = r.ClaimedAt.Format(time.RFC3339)
+	}
+
+	if r.ResolvedAt != nil {
+		report.ResolvedAt = r.ResolvedAt.Format(time.RFC3339)
+	}
+
+	return report
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func tokenHeader(username string) http.Header {
+	return http.Header{"Authorization": []string{fmt.Sprintf("Token %v", h.JWT.NewToken(username))}}
+}
+
+func postReport(t *testing.T, url string, reason string, header http.Header) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"report": map[string]string{"reason": reason, "details": "Reported by a test"},
+	})
+	return makeRequest(t, http.MethodPost, url, bytes.NewBuffer(jsonBody), header)
+}
+
+func postResolution(t *testing.T, reportID int, action string, header http.Header) *httptest.ResponseRecorder {
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"resolution": map[string]string{"action": action},
+	})
+	return makeRequest(t, http.MethodPost, "/api/moderation/reports/"+strconv.Itoa(reportID)+"/resolve", bytes.NewBuffer(jsonBody), header)
+}
+
+func Test_ReportArticle(t *testing.T) {
+	author := articles[2].User
+	moderator := tokenHeader("user5")
+
+	a := models.NewArticle("Reported Article", "Reported Article description", "Reported Article body", &author)
+	if err := h.DB.CreateArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	reportsToHide := models.ReportsToHide
+	models.ReportsToHide = 2
+	defer func() { models.ReportsToHide = reportsToHide }()
+
+	url := "/api/articles/" + a.Slug + "/report"
+
+	if Code := postReport(t, url, "rude", tokenHeader("user6")).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject an unknown reason: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	recorder := postReport(t, url, "spam", tokenHeader("user6"))
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("should return a 201 status code: got %v want %v", recorder.Code, http.StatusCreated)
+	}
+
+	var reportResponse ReportJSON
+	json.NewDecoder(recorder.Body).Decode(&reportResponse)
+
+	if reportResponse.Report.Status != models.ReportOpen || reportResponse.Report.ArticleSlug != a.Slug {
+		t.Errorf("should open a report of the article: got %v", reportResponse.Report)
+	}
+
+	if Code := postReport(t, url, "spam", tokenHeader("user6")).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should not report the same content twice: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should not hide the article before enough reports: got %v want %v", Code, http.StatusOK)
+	}
+
+	postReport(t, url, "harassment", tokenHeader("user7"))
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should hide the article reported by enough users: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, moderator).Body).Decode(&articleResponse)
+
+	if !articleResponse.Article.Hidden {
+		t.Errorf("should show the hidden article to the moderators")
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/moderation/reports", nil, tokenHeader("user6")).Code; Code != http.StatusForbidden {
+		t.Errorf("should only show the queue to the moderators: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	var reportsResponse ReportsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/moderation/reports", nil, moderator).Body).Decode(&reportsResponse)
+
+	var queued []Report
+	for _, report := range reportsResponse.Reports {
+		if report.ArticleSlug == a.Slug {
+			queued = append(queued, report)
+		}
+	}
+
+	if len(queued) != 2 || queued[0].Article == nil {
+		t.Fatalf("should queue the reports with their content: got %v want %v", len(queued), 2)
+	}
+
+	claimURL := "/api/moderation/reports/" + strconv.Itoa(queued[0].ID) + "/claim"
+
+	var claimResponse ReportJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, claimURL, nil, moderator).Body).Decode(&claimResponse)
+
+	if claimResponse.Report.Status != models.ReportClaimed || claimResponse.Report.Moderator == nil {
+		t.Errorf("should claim the report: got %v", claimResponse.Report)
+	}
+
+	DB.Model(&models.User{}).Where("username = ?", "user8").UpdateColumn("role", models.RoleModerator)
+	defer DB.Model(&models.User{}).Where("username = ?", "user8").UpdateColumn("role", "")
+
+	if Code := makeRequest(t, http.MethodPost, claimURL, nil, tokenHeader("user8")).Code; Code != http.StatusConflict {
+		t.Errorf("should not claim the report of another moderator: got %v want %v", Code, http.StatusConflict)
+	}
+
+	if Code := postResolution(t, queued[0].ID, "delete", moderator).Code; Code != http.StatusUnprocessableEntity {
+		t.Errorf("should reject an unknown action: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	if Code := postResolution(t, queued[0].ID, models.ActionDismiss, moderator).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should show the article again once the reports are dismissed: got %v want %v", Code, http.StatusOK)
+	}
+
+	var resolved models.Report
+	DB.First(&resolved, queued[1].ID)
+
+	if resolved.Status != models.ReportResolved || resolved.Action != models.ActionDismiss {
+		t.Errorf("should resolve every report of the article: got %v want %v", resolved.Status, models.ReportResolved)
+	}
+}
+
+func Test_ReportFlaggedArticle(t *testing.T) {
+	author := articles[2].User
+
+	a := models.NewArticle("Flagged Reported Article", "Flagged Reported Article description", "Flagged Reported Article body", &author)
+	if err := h.DB.CreateArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.DB.FlagContent(a, nil, models.ValidationErrors{"body": []string{models.BANNED_WORDS_MSG}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reportsToHide := models.ReportsToHide
+	models.ReportsToHide = 2
+	defer func() { models.ReportsToHide = reportsToHide }()
+
+	url := "/api/articles/" + a.Slug + "/report"
+
+	postReport(t, url, "spam", tokenHeader("user6"))
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should not count the report of the filters as a user: got %v want %v", Code, http.StatusOK)
+	}
+
+	postReport(t, url, "spam", tokenHeader("user7"))
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should hide the article reported by enough users: got %v want %v", Code, http.StatusNotFound)
+	}
+}
+
+func Test_ReportCommentAndSuspend(t *testing.T) {
+	article := articles[2]
+	author := articles[3].User
+	moderator := tokenHeader("user5")
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Abusive comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), tokenHeader(author.Username)).Body).Decode(&commentResponse)
+	commentURL := "/api/articles/" + article.Slug + "/comments/" + strconv.Itoa(commentResponse.Comment.ID)
+
+	if Code := postReport(t, "/api/articles/"+articles[1].Slug+"/comments/"+strconv.Itoa(commentResponse.Comment.ID)+"/report", "hate", tokenHeader("user6")).Code; Code != http.StatusNotFound {
+		t.Errorf("should not report the comment through another article: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	var reportResponse ReportJSON
+	json.NewDecoder(postReport(t, commentURL+"/report", "hate", tokenHeader("user6")).Body).Decode(&reportResponse)
+
+	if Code := postResolution(t, reportResponse.Report.ID, models.ActionSuspend, moderator).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	defer DB.Model(&models.User{}).Where("id = ?", author.ID).UpdateColumn("suspended_at", nil)
+
+	if Code := makeRequest(t, http.MethodGet, commentURL, nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should hide the comment: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, commentURL, nil, moderator).Code; Code != http.StatusOK {
+		t.Errorf("should show the hidden comment to the moderators: got %v want %v", Code, http.StatusOK)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), tokenHeader(author.Username)).Code; Code != http.StatusForbidden {
+		t.Errorf("should not let a suspended user post: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	if Code := postResolution(t, reportResponse.Report.ID, models.ActionHide, moderator).Code; Code != http.StatusConflict {
+		t.Errorf("should not resolve a report twice: got %v want %v", Code, http.StatusConflict)
+	}
+}
+
+func Test_HiddenCommentCount(t *testing.T) {
+	article := articles[2]
+	author := articles[3].User
+	authorHeader := tokenHeader(author.Username)
+
+	commentsCount := func() int {
+		var a models.Article
+		DB.First(&a, article.ID)
+		return a.CommentsCount
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Hidden then deleted comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), authorHeader).Body).Decode(&commentResponse)
+	commentID := strconv.Itoa(commentResponse.Comment.ID)
+	count := commentsCount()
+
+	var reportResponse ReportJSON
+	json.NewDecoder(postReport(t, "/api/articles/"+article.Slug+"/comments/"+commentID+"/report", "spam", tokenHeader("user6")).Body).Decode(&reportResponse)
+
+	if Code := postResolution(t, reportResponse.Report.ID, models.ActionHide, tokenHeader("user5")).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	if got := commentsCount(); got != count-1 {
+		t.Errorf("should uncount the hidden comment: got %v want %v", got, count-1)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+article.Slug+"/comments/"+commentID, nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Fatalf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	if got := commentsCount(); got != count-1 {
+		t.Errorf("should not uncount the deleted hidden comment twice: got %v want %v", got, count-1)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/comments/"+commentID+"/restore", nil, authorHeader).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	if got := commentsCount(); got != count-1 {
+		t.Errorf("should not count the restored comment while it is hidden: got %v want %v", got, count-1)
+	}
+}
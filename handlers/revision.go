@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+type Revision struct {
+	Number       int      `json:"number"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Body         string   `json:"body"`
+	Changes      []string `json:"changes"`
+	RestoredFrom int      `json:"restoredFrom,omitempty"`
+	CreatedAt    string   `json:"createdAt"`
+	Author       Author   `json:"author"`
+}
+
+type RevisionJSON struct {
+	Revision `json:"revision"`
+}
+
+type RevisionsJSON struct {
+	Revisions      []Revision `json:"revisions"`
+	RevisionsCount int        `json:"revisionsCount"`
+}
+
+type DiffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type FieldDiff struct {
+	Field string     `json:"field"`
+	Lines []DiffLine `json:"lines"`
+}
+
+type Diff struct {
+	From   int         `json:"from"`
+	To     int         `json:"to"`
+	Fields []FieldDiff `json:"fields"`
+}
+
+type DiffJSON struct {
+	Diff `json:"diff"`
+}
+
+// getRevisions handle GET /api/articles/:slug/revisions
+func (h *Handler) getRevisions(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	var revisions []models.Revision
+	if err := h.DB.GetRevisions(a, &revisions); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var revisionsJSON = RevisionsJSON{
+		Revisions:      []Revision{},
+		RevisionsCount: len(revisions),
+	}
+
+	for i := range revisions {
+		revisionsJSON.Revisions = append(revisionsJSON.Revisions, h.buildRevisionJSON(&revisions[i], u))
+	}
+
+	c.JSON(http.StatusOK, revisionsJSON)
+}
+
+// getRevision handle GET /api/articles/:slug/revisions/:number
+func (h *Handler) getRevision(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	revision, ok := h.extractRevision(c, c.Param("number"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, RevisionJSON{h.buildRevisionJSON(revision, u)})
+}
+
+// diffRevisions handle GET /api/articles/:slug/revisions/:number/diff
+// The revision is compared to the 'to' query string param revision, the latest one by default.
+func (h *Handler) diffRevisions(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+
+	from, ok := h.extractRevision(c, c.Param("number"))
+	if !ok {
+		return
+	}
+
+	var to *models.Revision
+
+	if number := c.Query("to"); number != "" {
+		if to, ok = h.extractRevision(c, number); !ok {
+			return
+		}
+	} else {
+		var revisions []models.Revision
+		if err := h.DB.GetRevisions(a, &revisions); err != nil {
+			c.String(http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		to = &revisions[len(revisions)-1]
+	}
+
+	diff := Diff{From: from.Number, To: to.Number, Fields: []FieldDiff{}}
+
+	for _, field := range models.DiffRevisions(from, to) {
+		fieldDiff := FieldDiff{Field: field.Field}
+		for _, line := range field.Lines {
+			fieldDiff.Lines = append(fieldDiff.Lines, DiffLine{line.Op, line.Text})
+		}
+		diff.Fields = append(diff.Fields, fieldDiff)
+	}
+
+	c.JSON(http.StatusOK, DiffJSON{diff})
+}
+
+// restoreRevision handle POST /api/articles/:slug/revisions/:number/restore
+func (h *Handler) restoreRevision(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	if !a.IsOwnedBy(u.Username) {
+		err := fmt.Errorf("You don't have the permission to restore this article")
+		c.String(http.StatusForbidden, err.Error())
+		return
+	}
+
+	revision, ok := h.extractRevision(c, c.Param("number"))
+	if !ok {
+		return
+	}
+
+	if err := h.DB.RestoreRevision(a, revision, u); err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	articleJSON := ArticleJSON{
+		Article: h.buildArticleJSON(a, u),
+	}
+
+	c.JSON(http.StatusOK, articleJSON)
+}
+
+// extractRevision fetch the revision of the fetched article with the given number,
+// it responds with a 400 or a 404 and returns false when it can't.
+func (h *Handler) extractRevision(c *gin.Context, number string) (*models.Revision, bool) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return nil, false
+	}
+
+	var revision models.Revision
+	if err := h.DB.GetRevision(a, n, &revision); err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return nil, false
+	}
+
+	return &revision, true
+}
+
+func (h *Handler) buildRevisionJSON(r *models.Revision, u *models.User) Revision {
+	following := false
+
+	if (u != &models.User{}) {
+		following = h.DB.IsFollowing(u.ID, r.User.ID)
+	}
+
+	return Revision{
+		Number:       r.Number,
+		Title:        r.Title,
+		Description:  r.Description,
+		Body:         r.Body,
+		Changes:      r.ChangedFields(),
+		RestoredFrom: r.RestoredFrom,
+		CreatedAt:    r.CreatedAt.Format(time.RFC3339),
+		Author: Author{
+			Username:  r.User.Username,
+			Bio:       r.User.Bio,
+			Image:     r.User.Image,
+			Following: following,
+		},
+	}
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/auth"
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func Test_Revisions(t *testing.T) {
+	author := articles[2].User
+	other := articles[1].User
+
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+	otherHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(other.Username))}}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"article": map[string]interface{}{
+			"title":       "Revised Article",
+			"description": "Revised Article description",
+			"body":        "first line\nsecond line",
+		},
+	})
+
+	recorder := makeRequest(t, http.MethodPost, "/api/articles", bytes.NewBuffer(jsonBody), authorHeader)
+	if Code := recorder.Code; Code != http.StatusCreated {
+		t.Fatalf("should return a 201 status code: got %v want %v", Code, http.StatusCreated)
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(recorder.Body).Decode(&articleResponse)
+	slug := articleResponse.Article.Slug
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{
+		"article": map[string]interface{}{
+			"body": "first line\nsecond line edited",
+		},
+	})
+
+	if Code := makeRequest(t, http.MethodPut, "/api/articles/"+slug, bytes.NewBuffer(jsonBody), authorHeader).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	var revisionsResponse RevisionsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions", nil, nil).Body).Decode(&revisionsResponse)
+
+	if count := revisionsResponse.RevisionsCount; count != 2 {
+		t.Fatalf("should record a revision on creation and on update: got %v want %v", count, 2)
+	}
+
+	revision := revisionsResponse.Revisions[1]
+	if !reflect.DeepEqual(revision.Changes, []string{"body"}) {
+		t.Errorf("should record the changed fields: got %v want %v", revision.Changes, []string{"body"})
+	}
+
+	if revision.Author.Username != author.Username {
+		t.Errorf("should record the editor: got %v want %v", revision.Author.Username, author.Username)
+	}
+
+	var diffResponse DiffJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions/1/diff?to=2", nil, nil).Body).Decode(&diffResponse)
+
+	expectedDiff := []FieldDiff{{
+		Field: "body",
+		Lines: []DiffLine{
+			{models.DiffEqual, "first line"},
+			{models.DiffDelete, "second line"},
+			{models.DiffInsert, "second line edited"},
+		},
+	}}
+
+	if !reflect.DeepEqual(diffResponse.Diff.Fields, expectedDiff) {
+		t.Errorf("should return the diff between the revisions: got %v want %v", diffResponse.Diff.Fields, expectedDiff)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions/first", nil, nil).Code; Code != http.StatusBadRequest {
+		t.Errorf("should return a 400 status code: got %v want %v", Code, http.StatusBadRequest)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions/42", nil, nil).Code; Code != http.StatusNotFound {
+		t.Errorf("should return a 404 status code: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/articles/"+slug+"/revisions/1/restore", nil, otherHeader).Code; Code != http.StatusForbidden {
+		t.Errorf("should return a 403 status code: got %v want %v", Code, http.StatusForbidden)
+	}
+
+	recorder = makeRequest(t, http.MethodPost, "/api/articles/"+slug+"/revisions/1/restore", nil, authorHeader)
+	if Code := recorder.Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	articleResponse = ArticleJSON{}
+	json.NewDecoder(recorder.Body).Decode(&articleResponse)
+
+	if body := articleResponse.Article.Body; body != "first line\nsecond line" {
+		t.Errorf("should restore the revision content: got %v want %v", body, "first line\nsecond line")
+	}
+
+	var revisionResponse RevisionJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions/3", nil, nil).Body).Decode(&revisionResponse)
+
+	if restoredFrom := revisionResponse.Revision.RestoredFrom; restoredFrom != 1 {
+		t.Errorf("should record the restoration as a new revision: got %v want %v", restoredFrom, 1)
+	}
+
+	a, _ := h.DB.GetArticle(slug)
+	DB.Where("article_id = ? AND number = ?", a.ID, 2).Delete(models.Revision{})
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{
+		"article": map[string]interface{}{
+			"body": "first line\nsecond line edited again",
+		},
+	})
+
+	makeRequest(t, http.MethodPut, "/api/articles/"+slug, bytes.NewBuffer(jsonBody), authorHeader)
+
+	revisionsResponse = RevisionsJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+slug+"/revisions", nil, nil).Body).Decode(&revisionsResponse)
+
+	if last := revisionsResponse.Revisions[len(revisionsResponse.Revisions)-1]; last.Number != 4 {
+		t.Errorf("should number the revision after the last one: got %v want %v", last.Number, 4)
+	}
+
+	if err := DB.Create(&models.Revision{ArticleID: a.ID, Number: 4}).Error; err == nil {
+		t.Errorf("should not record two revisions with the same number")
+	}
+}
+
+func Test_SaveArticleRevisionKeepsCounters(t *testing.T) {
+	author := articles[2].User
+
+	created := models.NewArticle("Edited Article", "Edited Article description", "Edited Article body", &author)
+	if err := h.DB.CreateArticle(created); err != nil {
+		t.Fatal(err)
+	}
+
+	// The article is loaded at the start of the request, it is favorited and commented before the edit is saved
+	a, err := h.DB.GetArticle(created.Slug)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	DB.Model(&models.Article{}).Where("id = ?", a.ID).UpdateColumns(map[string]interface{}{"favorites_count": 99, "comments_count": 42})
+
+	a.Body = "Edited Article body edited"
+	if err := h.DB.SaveArticleRevision(a, &author); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved models.Article
+	DB.First(&saved, a.ID)
+
+	if saved.Body != a.Body {
+		t.Errorf("should save the edit: got %v want %v", saved.Body, a.Body)
+	}
+
+	if saved.FavoritesCount != 99 || saved.CommentsCount != 42 {
+		t.Errorf("should not overwrite the counters of the article: got %v and %v want %v and %v", saved.FavoritesCount, saved.CommentsCount, 99, 42)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+// StreamHeartbeat is how often a comment is sent on the idle streams so the proxies
+// don't close them, it can be changed at startup to fit the deployment.
+var StreamHeartbeat = 15 * time.Second
+
+// Names of the events sent on the streams
+const (
+	commentCreatedEvent = "comment.created"
+	commentDeletedEvent = "comment.deleted"
+	notificationEvent   = "notification"
+)
+
+type deletedCommentEvent struct {
+	ID int `json:"id"`
+}
+
+func articleTopic(a *models.Article) string {
+	return fmt.Sprintf("article:%v", a.ID)
+}
+
+func userTopic(userID int) string {
+	return fmt.Sprintf("user:%v", userID)
+}
+
+// streamArticle handle GET /api/articles/:slug/stream
+// It pushes the comments created or deleted on the article, but the ones of the users
+// the current user blocks or mutes, like GET /api/articles/:slug/comments hides them.
+func (h *Handler) streamArticle(c *gin.Context) {
+	a := getFromContext(fetchedArticleKey, c).(*models.Article)
+	u := getFromContext(currentUserKey, c).(*models.User)
+	h.stream(c, articleTopic(a), h.hiddenUsers(u))
+}
+
+// streamNotifications handle GET /api/notifications/stream
+// It pushes the new notifications of the current user.
+func (h *Handler) streamNotifications(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+	h.stream(c, userTopic(u.ID), nil)
+}
+
+// queryToken move the 'token' query string param of the streams to the Authorization
+// header when it has none, the browsers can't set it on an EventSource. It must precede the logger,
+// so the token is never logged, and getCurrentUser, so the stream is limited by the user budget.
+func (h *Handler) queryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !isStream(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		query := c.Request.URL.Query()
+		if token := query.Get("token"); token != "" {
+			if c.Request.Header.Get("Authorization") == "" {
+				c.Request.Header.Set("Authorization", "Token "+token)
+			}
+
+			query.Del("token")
+			c.Request.URL.RawQuery = query.Encode()
+			c.Request.RequestURI = c.Request.URL.RequestURI()
+		}
+
+		c.Next()
+	}
+}
+
+// isStream check if the path is the one of a stream, the only routes authenticated by queryToken
+func isStream(path string) bool {
+	return path == "/api/notifications/stream" ||
+		(strings.HasPrefix(path, "/api/articles/") && strings.HasSuffix(path, "/stream"))
+}
+
+// publishComment push the event of a comment written by the user to the stream of its article
+func (h *Handler) publishComment(a *models.Article, name string, userID int, data interface{}) {
+	if err := h.Hub.PublishBy(articleTopic(a), name, userID, data); err != nil {
+		h.Logger.Println("stream:", err)
+	}
+}
+
+// publishNotification push the saved notification to the stream of its user
+func (h *Handler) publishNotification(n *models.Notification) {
+	u := &models.User{ID: n.UserID}
+
+	var notification models.Notification
+	if err := h.DB.GetNotification(u, n.ID, &notification); err != nil {
+		h.Logger.Println("stream:", err)
+		return
+	}
+
+	if err := h.Hub.Publish(userTopic(n.UserID), notificationEvent, h.buildNotificationJSON(&notification, u)); err != nil {
+		h.Logger.Println("stream:", err)
+	}
+}
+
+// stream send the events of the topic as Server-Sent Events until the client disconnects,
+// but the ones of the hidden users. The events missed since the Last-Event-ID header are sent first.
+func (h *Handler) stream(c *gin.Context, topic string, hidden map[int]bool) {
+	lastEventID, _ := strconv.ParseInt(c.Request.Header.Get("Last-Event-ID"), 10, 64)
+
+	missed, subscription := h.Hub.Subscribe(topic, lastEventID)
+	defer h.Hub.Unsubscribe(subscription)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range missed {
+		if !hidden[event.UserID] {
+			writeEvent(c.Writer, event)
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, open := <-subscription.Events:
+			// The subscriber lagged too far behind, the client resumes with Last-Event-ID
+			if !open {
+				return
+			}
+			if hidden[event.UserID] {
+				continue
+			}
+			writeEvent(c.Writer, event)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event Event) {
+	fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Name, event.Data)
+}
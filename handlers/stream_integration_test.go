@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+// openStream connect to the stream and returns its lines until cancel is called
+func openStream(t *testing.T, server *httptest.Server, url string, header http.Header) (*http.Response, <-chan string, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if header != nil {
+		req.Header = header
+	}
+
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		t.Fatal(err)
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return resp, lines, cancel
+}
+
+// nextEvent read the fields of the next event of the stream, the comments are skipped
+func nextEvent(t *testing.T, lines <-chan string) map[string]string {
+	event := map[string]string{}
+	timeout := time.After(2 * time.Second)
+
+	for {
+		select {
+		case line, open := <-lines:
+			if !open {
+				t.Fatal("the stream was closed")
+			}
+
+			if line == "" && len(event) > 0 {
+				return event
+			}
+
+			if field := strings.SplitN(line, ": ", 2); len(field) == 2 && field[0] != "" {
+				event[field[0]] = field[1]
+			}
+		case <-timeout:
+			t.Fatal("no event was received")
+		}
+	}
+}
+
+func Test_StreamComments(t *testing.T) {
+	server := httptest.NewServer(h.InitRoutes())
+	defer server.Close()
+
+	a := articles[2]
+	header := tokenHeader("user6")
+
+	resp, lines, cancel := openStream(t, server, "/api/articles/"+a.Slug+"/stream", nil)
+	defer cancel()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("should stream the events: got %v", contentType)
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Live comment"},
+	})
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", bytes.NewBuffer(jsonBody), header).Body).Decode(&commentResponse)
+
+	created := nextEvent(t, lines)
+
+	var comment Comment
+	json.Unmarshal([]byte(created["data"]), &comment)
+
+	if created["event"] != commentCreatedEvent || comment.ID != commentResponse.Comment.ID || comment.Body != "Live comment" {
+		t.Errorf("should push the new comment: got %v", created)
+	}
+
+	makeRequest(t, http.MethodDelete, "/api/articles/"+a.Slug+"/comments/"+strconv.Itoa(comment.ID), nil, header)
+
+	deleted := nextEvent(t, lines)
+
+	if deleted["event"] != commentDeletedEvent || deleted["data"] != `{"id":`+strconv.Itoa(comment.ID)+`}` {
+		t.Errorf("should push the deleted comment: got %v", deleted)
+	}
+
+	cancel()
+
+	// A client resuming the stream receives the events it missed
+	_, lines, cancel = openStream(t, server, "/api/articles/"+a.Slug+"/stream", http.Header{"Last-Event-Id": []string{created["id"]}})
+	defer cancel()
+
+	if missed := nextEvent(t, lines); missed["id"] != deleted["id"] || missed["event"] != commentDeletedEvent {
+		t.Errorf("should resume the stream after Last-Event-ID: got %v want %v", missed, deleted)
+	}
+
+	resp, _, cancel = openStream(t, server, "/api/articles/unknown-slug/stream", nil)
+	cancel()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("should return a 404 for an unknown article: got %v want %v", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func Test_StreamCommentsListedOnly(t *testing.T) {
+	server := httptest.NewServer(h.InitRoutes())
+	defer server.Close()
+
+	a := articles[2]
+	reader := tokenHeader("user7")
+
+	contentFilters := models.ContentFilters
+	models.ContentFilters = []models.FilterRule{{Filter: models.LinkFilter{MaxLinks: 0}, Action: models.FilterFlag}}
+	defer func() { models.ContentFilters = contentFilters }()
+
+	makeRequest(t, http.MethodPost, "/api/profiles/user6/mute", nil, reader)
+	defer makeRequest(t, http.MethodDelete, "/api/profiles/user6/mute", nil, reader)
+
+	_, lines, cancel := openStream(t, server, "/api/articles/"+a.Slug+"/stream?token="+h.JWT.NewToken("user7"), nil)
+	defer cancel()
+
+	postComment := func(body string, header http.Header) int {
+		jsonBody, _ := json.Marshal(map[string]interface{}{
+			"comment": map[string]string{"body": body},
+		})
+
+		var commentResponse CommentJSON
+		json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+a.Slug+"/comments", bytes.NewBuffer(jsonBody), header).Body).Decode(&commentResponse)
+		return commentResponse.Comment.ID
+	}
+
+	postComment("Comment of a muted user", tokenHeader("user6"))
+	flaggedID := postComment("Flagged comment https://spam.example", tokenHeader("user8"))
+	listedID := postComment("Listed comment", tokenHeader("user8"))
+
+	var comment Comment
+	json.Unmarshal([]byte(nextEvent(t, lines)["data"]), &comment)
+
+	if comment.ID != listedID {
+		t.Fatalf("should only push the comments listed to the reader: got %v want %v", comment.ID, listedID)
+	}
+
+	var reportsResponse ReportsJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/moderation/reports", nil, tokenHeader("user5")).Body).Decode(&reportsResponse)
+
+	for _, report := range reportsResponse.Reports {
+		if report.Comment != nil && report.Comment.ID == flaggedID {
+			postResolution(t, report.ID, models.ActionDismiss, tokenHeader("user5"))
+		}
+	}
+
+	comment = Comment{}
+	json.Unmarshal([]byte(nextEvent(t, lines)["data"]), &comment)
+
+	if comment.ID != flaggedID {
+		t.Errorf("should push the flagged comment once it is cleared: got %v want %v", comment.ID, flaggedID)
+	}
+}
+
+func Test_StreamNotifications(t *testing.T) {
+	server := httptest.NewServer(h.InitRoutes())
+	defer server.Close()
+
+	heartbeat := StreamHeartbeat
+	StreamHeartbeat = 50 * time.Millisecond
+	defer func() { StreamHeartbeat = heartbeat }()
+
+	a := articles[2]
+	author := a.User
+
+	resp, _, cancel := openStream(t, server, "/api/notifications/stream", nil)
+	cancel()
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("should only stream the notifications of a user: got %v want %v", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	// The browsers can't set the Authorization header of an EventSource
+	_, lines, cancel := openStream(t, server, "/api/notifications/stream?token="+h.JWT.NewToken(author.Username), nil)
+	defer cancel()
+
+	select {
+	case line := <-lines:
+		if line != ": heartbeat" {
+			t.Errorf("should send a heartbeat on the idle stream: got %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Errorf("should send a heartbeat on the idle stream")
+	}
+
+	makeRequest(t, http.MethodPost, "/api/profiles/"+author.Username+"/follow", nil, tokenHeader("user7"))
+	defer makeRequest(t, http.MethodDelete, "/api/profiles/"+author.Username+"/follow", nil, tokenHeader("user7"))
+
+	event := nextEvent(t, lines)
+
+	var notification Notification
+	json.Unmarshal([]byte(event["data"]), &notification)
+
+	if event["event"] != notificationEvent || notification.Kind != models.NotifyFollow || notification.Actor.Username != "user7" {
+		t.Errorf("should push the new notification: got %v", event)
+	}
+}
+
+func Test_StreamQueryToken(t *testing.T) {
+	var logs bytes.Buffer
+	writer := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	server := httptest.NewServer(h.InitRoutes())
+	gin.DefaultWriter = writer
+	defer server.Close()
+
+	rateLimits, rateLimiter := RateLimits, h.RateLimiter
+	RateLimits = map[string]RateLimit{ReadRoutes: {Requests: 1, Period: time.Minute}}
+	h.RateLimiter = NewMemoryRateLimitStore()
+	defer func() { RateLimits, h.RateLimiter = rateLimits, rateLimiter }()
+
+	// The budget of the anonymous requests of the client is used up
+	resp, err := http.Get(server.URL + "/api/articles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	token := h.JWT.NewToken("user6")
+	resp, _, cancel := openStream(t, server, "/api/notifications/stream?token="+token, nil)
+	cancel()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("should limit the stream with the budget of the user: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	// Close waits for the requests to be logged
+	server.Close()
+
+	if !strings.Contains(logs.String(), "/api/notifications/stream") {
+		t.Fatalf("should log the stream request")
+	}
+
+	if strings.Contains(logs.String(), token) {
+		t.Errorf("should not log the token")
+	}
+}
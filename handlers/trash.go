@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+	"gopkg.in/gin-gonic/gin.v1"
+)
+
+type TrashedArticle struct {
+	Article
+	DeletedAt string `json:"deletedAt"`
+	PurgeAt   string `json:"purgeAt"`
+}
+
+type TrashedComment struct {
+	Comment
+	ArticleSlug string `json:"articleSlug"`
+	DeletedAt   string `json:"deletedAt"`
+	PurgeAt     string `json:"purgeAt"`
+}
+
+type TrashJSON struct {
+	Articles []TrashedArticle `json:"articles"`
+	Comments []TrashedComment `json:"comments"`
+}
+
+// getTrash handle GET /api/trash
+// It lists the articles and comments the current user deleted and can still restore.
+func (h *Handler) getTrash(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+	now := time.Now()
+
+	articles, err := h.DB.GetTrashedArticles(u, now)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	comments, err := h.DB.GetTrashedComments(u, now)
+	if err != nil {
+		c.String(http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	var trashJSON = TrashJSON{
+		Articles: []TrashedArticle{},
+		Comments: []TrashedComment{},
+	}
+
+	for i := range articles {
+		trashJSON.Articles = append(trashJSON.Articles, TrashedArticle{
+			Article:   h.buildArticleJSON(&articles[i], u),
+			DeletedAt: articles[i].DeletedAt.Format(time.RFC3339),
+			PurgeAt:   models.PurgeAt(*articles[i].DeletedAt).Format(time.RFC3339),
+		})
+	}
+
+	for i := range comments {
+		trashJSON.Comments = append(trashJSON.Comments, TrashedComment{
+			Comment:     h.buildCommentJSON(&comments[i], u),
+			ArticleSlug: comments[i].Article.Slug,
+			DeletedAt:   comments[i].DeletedAt.Format(time.RFC3339),
+			PurgeAt:     models.PurgeAt(*comments[i].DeletedAt).Format(time.RFC3339),
+		})
+	}
+
+	c.JSON(http.StatusOK, trashJSON)
+}
+
+// restoreArticle handle POST /api/trash/articles/:slug/restore
+func (h *Handler) restoreArticle(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	a, err := h.DB.RestoreArticle(u, c.Param("slug"), time.Now())
+	if err == models.ErrorSlugTaken {
+		c.JSON(http.StatusUnprocessableEntity, errorJSON{models.ValidationErrors{"slug": []string{err.Error()}}})
+		return
+	}
+
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+
+	articleJSON := ArticleJSON{
+		Article: h.buildArticleJSON(a, u),
+	}
+
+	c.JSON(http.StatusOK, articleJSON)
+}
+
+// restoreComment handle POST /api/trash/comments/:commentID/restore
+func (h *Handler) restoreComment(c *gin.Context) {
+	u := getFromContext(currentUserKey, c).(*models.User)
+
+	commentID, err := strconv.Atoi(c.Param("commentID"))
+	if err != nil {
+		c.String(http.StatusBadRequest, http.StatusText(http.StatusBadRequest))
+		return
+	}
+
+	comment, err := h.DB.RestoreComment(u, commentID, time.Now())
+	if err != nil {
+		c.String(http.StatusNotFound, err.Error())
+		return
+	}
+
+	commentJSON := CommentJSON{
+		Comment: h.buildCommentJSON(comment, u),
+	}
+
+	c.JSON(http.StatusOK, commentJSON)
+}
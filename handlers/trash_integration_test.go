@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/auth"
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/models"
+)
+
+func Test_TrashArticle(t *testing.T) {
+	author := articles[0].User
+	other := articles[1].User
+
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+	otherHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(other.Username))}}
+
+	a := models.NewArticle("Trashed Article", "Trashed Article description", "Trashed Article body", &author)
+	if err := h.DB.CreateArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+a.Slug, nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Fatalf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, authorHeader).Code; Code != http.StatusNotFound {
+		t.Errorf("should not return a deleted article: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	var articlesResponse ArticlesJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles?author="+author.Username, nil, nil).Body).Decode(&articlesResponse)
+
+	for _, article := range articlesResponse.Articles {
+		if article.Slug == a.Slug {
+			t.Errorf("should not list a deleted article")
+		}
+	}
+
+	articlesResponse = ArticlesJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/search?q=trashed", nil, nil).Body).Decode(&articlesResponse)
+
+	if count := len(articlesResponse.Articles); count != 0 {
+		t.Errorf("should not find a deleted article: got %v want %v", count, 0)
+	}
+
+	var trashResponse TrashJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/trash", nil, authorHeader).Body).Decode(&trashResponse)
+
+	if len(trashResponse.Articles) != 1 || trashResponse.Articles[0].Slug != a.Slug {
+		t.Fatalf("should list the deleted article in the trash of its author: got %v want %v", len(trashResponse.Articles), 1)
+	}
+
+	if trashResponse.Articles[0].PurgeAt == "" {
+		t.Errorf("should tell when the article will be purged")
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/articles/"+a.Slug+"/restore", nil, otherHeader).Code; Code != http.StatusNotFound {
+		t.Errorf("should not restore the article of another user: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/articles/"+a.Slug+"/restore", nil, authorHeader).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+a.Slug, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should return the restored article: got %v want %v", Code, http.StatusOK)
+	}
+
+	articlesResponse = ArticlesJSON{}
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/search?q=trashed", nil, nil).Body).Decode(&articlesResponse)
+
+	if count := len(articlesResponse.Articles); count != 1 {
+		t.Errorf("should find the restored article: got %v want %v", count, 1)
+	}
+}
+
+func Test_RestoreArticleSlugTaken(t *testing.T) {
+	author := articles[0].User
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+
+	trashed := models.NewArticle("Reused Slug", "Reused Slug description", "Reused Slug body", &author)
+	if err := h.DB.CreateArticle(trashed); err != nil {
+		t.Fatal(err)
+	}
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+trashed.Slug, nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Fatalf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	live := models.NewArticle("Reused Slug", "Reused Slug newer description", "Reused Slug newer body", &author)
+	if err := h.DB.CreateArticle(live); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := makeRequest(t, http.MethodPost, "/api/trash/articles/"+trashed.Slug+"/restore", nil, authorHeader)
+	if Code := recorder.Code; Code != http.StatusUnprocessableEntity {
+		t.Fatalf("should not restore an article over a live one: got %v want %v", Code, http.StatusUnprocessableEntity)
+	}
+
+	var errorJSON errorJSON
+	json.NewDecoder(recorder.Body).Decode(&errorJSON)
+
+	if _, present := errorJSON.Errors["slug"]; !present {
+		t.Errorf("should return an error on the slug: got %v want %v", present, true)
+	}
+
+	var articleResponse ArticleJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/articles/"+live.Slug, nil, nil).Body).Decode(&articleResponse)
+
+	if description := articleResponse.Article.Description; description != live.Description {
+		t.Errorf("should keep serving the live article: got %v want %v", description, live.Description)
+	}
+
+	var trashResponse TrashJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/trash", nil, authorHeader).Body).Decode(&trashResponse)
+
+	if len(trashResponse.Articles) != 1 || trashResponse.Articles[0].Slug != trashed.Slug {
+		t.Errorf("should keep the article in the trash: got %v want %v", len(trashResponse.Articles), 1)
+	}
+}
+
+func Test_TrashComment(t *testing.T) {
+	article := articles[1]
+	author := article.User
+
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Trashed comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+article.Slug+"/comments", bytes.NewBuffer(jsonBody), authorHeader).Body).Decode(&commentResponse)
+	commentID := strconv.Itoa(commentResponse.Comment.ID)
+
+	if Code := makeRequest(t, http.MethodDelete, "/api/articles/"+article.Slug+"/comments/"+commentID, nil, authorHeader).Code; Code != http.StatusNoContent {
+		t.Fatalf("should return a 204 status code: got %v want %v", Code, http.StatusNoContent)
+	}
+
+	var trashResponse TrashJSON
+	json.NewDecoder(makeRequest(t, http.MethodGet, "/api/trash", nil, authorHeader).Body).Decode(&trashResponse)
+
+	var trashed *TrashedComment
+	for i, comment := range trashResponse.Comments {
+		if comment.ID == commentResponse.Comment.ID {
+			trashed = &trashResponse.Comments[i]
+		}
+	}
+
+	if trashed == nil || trashed.ArticleSlug != article.Slug {
+		t.Fatalf("should list the deleted comment in the trash of its author")
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/comments/first/restore", nil, authorHeader).Code; Code != http.StatusBadRequest {
+		t.Errorf("should return a 400 status code: got %v want %v", Code, http.StatusBadRequest)
+	}
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/comments/"+commentID+"/restore", nil, authorHeader).Code; Code != http.StatusOK {
+		t.Fatalf("should return a 200 status code: got %v want %v", Code, http.StatusOK)
+	}
+
+	if Code := makeRequest(t, http.MethodGet, "/api/articles/"+article.Slug+"/comments/"+commentID, nil, nil).Code; Code != http.StatusOK {
+		t.Errorf("should return the restored comment: got %v want %v", Code, http.StatusOK)
+	}
+}
+
+func Test_PurgeTrash(t *testing.T) {
+	author := articles[0].User
+	authorHeader := http.Header{"Authorization": []string{fmt.Sprintf("Token %v", auth.NewJWT().NewToken(author.Username))}}
+
+	a := models.NewArticle("Expired Article", "Expired Article description", "Expired Article body", &author)
+	if err := h.DB.CreateArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.DB.DeleteArticle(a); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-models.TrashRetention - time.Hour)
+	DB.Unscoped().Model(a).UpdateColumn("deleted_at", expired)
+
+	if Code := makeRequest(t, http.MethodPost, "/api/trash/articles/"+a.Slug+"/restore", nil, authorHeader).Code; Code != http.StatusNotFound {
+		t.Errorf("should not restore an article after the retention: got %v want %v", Code, http.StatusNotFound)
+	}
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"comment": map[string]string{"body": "Expired comment"},
+	})
+
+	var commentResponse CommentJSON
+	json.NewDecoder(makeRequest(t, http.MethodPost, "/api/articles/"+articles[0].Slug+"/comments", bytes.NewBuffer(jsonBody), authorHeader).Body).Decode(&commentResponse)
+
+	comment := &models.Comment{}
+	if err := h.DB.GetComment(articles[0], commentResponse.Comment.ID, comment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.DB.UpdateComment(comment, "Edited expired comment"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := h.DB.DeleteComment(comment); err != nil {
+		t.Fatal(err)
+	}
+
+	DB.Unscoped().Model(comment).UpdateColumn("deleted_at", expired)
+
+	if _, err := h.DB.PurgeTrash(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	DB.Unscoped().Model(&models.Article{}).Where("id = ?", a.ID).Count(&count)
+
+	if count != 0 {
+		t.Errorf("should purge the expired article: got %v want %v", count, 0)
+	}
+
+	DB.Unscoped().Model(&models.Comment{}).Where("id = ?", comment.ID).Count(&count)
+
+	if count != 0 {
+		t.Errorf("should purge the expired comment: got %v want %v", count, 0)
+	}
+
+	DB.Model(&models.CommentVersion{}).Where("comment_id = ?", comment.ID).Count(&count)
+
+	if count != 0 {
+		t.Errorf("should purge the versions of the expired comment: got %v want %v", count, 0)
+	}
+}
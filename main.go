@@ -1,8 +1,12 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/auth"
 	"github.com/guillaumemaka/realworld-starter-kit-go-gin/handlers"
@@ -10,12 +14,21 @@ import (
 )
 
 const (
-	DATABASE string = "conduit.db"
-	DIALECT  string = "sqlite3"
-	PORT     string = ":8080"
+	DATABASE         string        = "conduit.db"
+	DIALECT          string        = "sqlite3"
+	PORT             string        = ":8080"
+	TRENDING_REFRESH time.Duration = 10 * time.Minute
+	PUBLISH_INTERVAL time.Duration = time.Minute
+	PURGE_INTERVAL   time.Duration = time.Hour
 )
 
+// The counters and backfills go through whole tables, they are run on demand
+// with -reconcile (e.g. after an upgrade) rather than on every boot.
+var reconcile = flag.Bool("reconcile", false, "recount the cached counters, backfill the articles, rebuild the search index and exit")
+
 func main() {
+	flag.Parse()
+
 	logger := log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
 
 	db, err := models.NewDB(DIALECT, DATABASE)
@@ -25,10 +38,110 @@ func main() {
 
 	db.InitSchema()
 
+	if *reconcile {
+		if err := db.Reconcile(); err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
+	if maxLimit, err := strconv.Atoi(os.Getenv("MAX_PAGE_SIZE")); err == nil && maxLimit > 0 {
+		models.MaxLimit = maxLimit
+	}
+
+	if length, err := strconv.Atoi(os.Getenv("MAX_BODY_LENGTH")); err == nil && length > 0 {
+		models.MaxBodyLength = length
+	}
+
+	if depth, err := strconv.Atoi(os.Getenv("MAX_COMMENT_DEPTH")); err == nil && depth >= 0 {
+		models.MaxCommentDepth = depth
+	}
+
+	if window, err := strconv.Atoi(os.Getenv("COMMENT_EDIT_WINDOW_MINUTES")); err == nil && window > 0 {
+		models.CommentEditWindow = time.Duration(window) * time.Minute
+	}
+
+	if reports, err := strconv.Atoi(os.Getenv("REPORTS_TO_HIDE")); err == nil && reports >= 0 {
+		models.ReportsToHide = reports
+	}
+
+	if retention, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS")); err == nil && retention > 0 {
+		models.TrashRetention = time.Duration(retention) * 24 * time.Hour
+	}
+
+	if ttl, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS")); err == nil && ttl > 0 {
+		handlers.IdempotencyKeyTTL = time.Duration(ttl) * time.Hour
+	}
+
+	if heartbeat, err := strconv.Atoi(os.Getenv("STREAM_HEARTBEAT_SECONDS")); err == nil && heartbeat > 0 {
+		handlers.StreamHeartbeat = time.Duration(heartbeat) * time.Second
+	}
+
+	// The budgets are in requests per minute, 0 disables the rate limit of the group
+	for group, env := range map[string]string{
+		handlers.AuthRoutes:  "RATE_LIMIT_AUTH",
+		handlers.WriteRoutes: "RATE_LIMIT_WRITES",
+		handlers.ReadRoutes:  "RATE_LIMIT_READS",
+	} {
+		if requests, err := strconv.Atoi(os.Getenv(env)); err == nil && requests >= 0 {
+			handlers.RateLimits[group] = handlers.RateLimit{Requests: requests, Period: time.Minute}
+		}
+	}
+
+	if proxies := os.Getenv("TRUSTED_PROXIES"); proxies != "" {
+		handlers.TrustedProxies = strings.Split(proxies, ",")
+	}
+
+	if words := os.Getenv("FILTER_WORDS"); words != "" {
+		addContentFilter(logger, models.NewWordFilter(strings.Split(words, ",")), "FILTER_WORDS_ACTION", models.FilterReject)
+	}
+
+	if links, err := strconv.Atoi(os.Getenv("FILTER_MAX_LINKS")); err == nil && links >= 0 {
+		addContentFilter(logger, models.LinkFilter{MaxLinks: links}, "FILTER_LINKS_ACTION", models.FilterFlag)
+	}
+
+	if window, err := strconv.Atoi(os.Getenv("FILTER_DUPLICATE_WINDOW_MINUTES")); err == nil && window > 0 {
+		addContentFilter(logger, models.DuplicateFilter{Window: time.Duration(window) * time.Minute}, "FILTER_DUPLICATE_ACTION", models.FilterReject)
+	}
+
 	j := auth.NewJWT()
 	h := handlers.New(db, j, logger)
 
+	go h.Trending.Run(TRENDING_REFRESH, nil, func(err error) {
+		logger.Println("trending refresh:", err)
+	})
+
+	go models.RunEvery(PUBLISH_INTERVAL, nil, func() error {
+		_, err := db.PublishScheduledArticles(time.Now())
+		return err
+	}, func(err error) {
+		logger.Println("scheduled publication:", err)
+	})
+
+	go models.RunEvery(PURGE_INTERVAL, nil, func() error {
+		_, err := db.PurgeTrash(time.Now())
+		return err
+	}, func(err error) {
+		logger.Println("trash purge:", err)
+	})
+
 	router := h.InitRoutes()
 
 	router.Run(PORT)
 }
+
+// addContentFilter append the filter to the content filters with the action
+// read from the env variable, or the fallback action when it is not set.
+func addContentFilter(logger *log.Logger, filter models.ContentFilter, actionEnv string, fallback string) {
+	action := os.Getenv(actionEnv)
+	if action == "" {
+		action = fallback
+	}
+
+	rule, err := models.NewFilterRule(filter, action)
+	if err != nil {
+		logger.Fatal(actionEnv, ": ", err)
+	}
+
+	models.ContentFilters = append(models.ContentFilters, rule)
+}
@@ -0,0 +1,406 @@
+// Package markdown renders the markdown written by the users to HTML.
+//
+// The output is safe to embed in a page: the source is never copied as is,
+// every text is HTML escaped and the only tags are the ones generated by the
+// renderer. Raw HTML in the source is escaped and links or images whose URL
+// scheme is not http, https or mailto are rendered as plain text.
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	headingRe   = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	ruleRe      = regexp.MustCompile(`^ {0,3}(-( *-){2,}|\*( *\*){2,}|_( *_){2,}) *$`)
+	unorderedRe = regexp.MustCompile(`^ {0,3}[-*+]\s+(.*)$`)
+	orderedRe   = regexp.MustCompile(`^ {0,3}\d{1,9}[.)]\s+(.*)$`)
+	fenceRe     = regexp.MustCompile("^ {0,3}```\\s*([A-Za-z0-9_+-]*)\\s*$")
+	indentedRe  = regexp.MustCompile(`^( {4}|\t)`)
+	// tagRe match the tags generated by Render, the texts never contain '<' unescaped
+	tagRe = regexp.MustCompile(`<[^>]*>`)
+)
+
+// maxQuoteDepth is how deep the blockquotes can be nested,
+// the deeper ones are rendered as paragraphs.
+const maxQuoteDepth = 8
+
+// allowedSchemes are the URL schemes links and images can use,
+// URLs without scheme are relative and always allowed.
+var allowedSchemes = map[string]bool{
+	"http":   true,
+	"https":  true,
+	"mailto": true,
+}
+
+// Render returns the sanitized HTML of the given markdown source
+func Render(source string) string {
+	source = strings.Replace(source, "\r\n", "\n", -1)
+	source = strings.Replace(source, "\r", "\n", -1)
+
+	var out bytes.Buffer
+	renderBlocks(&out, strings.Split(source, "\n"), 0)
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// Text returns the text of the given markdown source without its formatting,
+// the blocks are separated by a new line.
+func Text(source string) string {
+	text := html.UnescapeString(tagRe.ReplaceAllString(Render(source), ""))
+
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Blocks																	 //
+///////////////////////////////////////////////////////////////////////////////
+
+// renderBlocks render the lines, depth is the number of enclosing blockquotes
+func renderBlocks(out *bytes.Buffer, lines []string, depth int) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case fenceRe.MatchString(line):
+			language := fenceRe.FindStringSubmatch(line)[1]
+			var code []string
+			for i++; i < len(lines) && !fenceRe.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			i++
+			renderCode(out, code, language)
+
+		case indentedRe.MatchString(line):
+			var code []string
+			for ; i < len(lines) && (indentedRe.MatchString(lines[i]) || strings.TrimSpace(lines[i]) == ""); i++ {
+				code = append(code, indentedRe.ReplaceAllString(lines[i], ""))
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			renderCode(out, code, "")
+
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			level := strconv.Itoa(len(m[1]))
+			out.WriteString("<h" + level + ">" + renderInline(m[2]) + "</h" + level + ">\n")
+			i++
+
+		case ruleRe.MatchString(line):
+			out.WriteString("<hr>\n")
+			i++
+
+		case depth < maxQuoteDepth && strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimLeft(lines[i], " "), ">"); i++ {
+				quoted := strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+				quote = append(quote, strings.TrimPrefix(quoted, " "))
+			}
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, quote, depth+1)
+			out.WriteString("</blockquote>\n")
+
+		case unorderedRe.MatchString(line):
+			i = renderList(out, lines, i, unorderedRe, "ul")
+
+		case orderedRe.MatchString(line):
+			i = renderList(out, lines, i, orderedRe, "ol")
+
+		default:
+			var paragraph []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && (len(paragraph) == 0 || !startsBlock(lines[i])); i++ {
+				paragraph = append(paragraph, strings.TrimSpace(lines[i]))
+			}
+			out.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+		}
+	}
+}
+
+// renderList render the list starting at lines[i] whose items match itemRe,
+// the indented lines following an item are part of it.
+// It returns the index of the line following the list.
+func renderList(out *bytes.Buffer, lines []string, i int, itemRe *regexp.Regexp, tag string) int {
+	out.WriteString("<" + tag + ">\n")
+
+	for i < len(lines) && itemRe.MatchString(lines[i]) {
+		item := []string{strings.TrimSpace(itemRe.FindStringSubmatch(lines[i])[1])}
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "" && !startsBlock(lines[i]); i++ {
+			item = append(item, strings.TrimSpace(lines[i]))
+		}
+		out.WriteString("<li>" + renderInline(strings.Join(item, " ")) + "</li>\n")
+	}
+
+	out.WriteString("</" + tag + ">\n")
+	return i
+}
+
+func renderCode(out *bytes.Buffer, lines []string, language string) {
+	class := ""
+	if language != "" {
+		class = fmt.Sprintf(` class="language-%v"`, language)
+	}
+
+	out.WriteString("<pre><code" + class + ">")
+	for _, line := range lines {
+		out.WriteString(html.EscapeString(line) + "\n")
+	}
+	out.WriteString("</code></pre>\n")
+}
+
+// startsBlock check if the line interrupts a paragraph or a list item
+func startsBlock(line string) bool {
+	return fenceRe.MatchString(line) ||
+		headingRe.MatchString(line) ||
+		ruleRe.MatchString(line) ||
+		strings.HasPrefix(strings.TrimLeft(line, " "), ">") ||
+		unorderedRe.MatchString(line) ||
+		orderedRe.MatchString(line)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Inlines																	 //
+///////////////////////////////////////////////////////////////////////////////
+
+// delimiterRun is a run of '*' or '_' in an inline text, the delimiters matched
+// by another run are rendered as the em and strong tags of the emphasis.
+type delimiterRun struct {
+	delimiter byte
+	// count is the number of delimiters not matched yet, they are rendered as is
+	count    int
+	canOpen  bool
+	canClose bool
+	// opens and closes are the tags of the emphasis the run opens or closes, innermost first
+	opens  []string
+	closes []string
+}
+
+// inlineNode is either a rendered HTML fragment or a delimiter run
+type inlineNode struct {
+	html string
+	run  *delimiterRun
+}
+
+// renderInline render the emphasis, code spans, links and images of text.
+// It runs in linear time: a code span or link which is not closed stops
+// the following ones from being searched for and the emphasis are matched
+// in a single pass with a stack of the delimiter runs.
+func renderInline(text string) string {
+	var nodes []inlineNode
+	var literal bytes.Buffer
+
+	flush := func() {
+		if literal.Len() > 0 {
+			nodes = append(nodes, inlineNode{html: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	noCodeSpan, noLink := false, false
+
+	for i := 0; i < len(text); {
+		rest := text[i:]
+
+		switch {
+		case rest[0] == '\\' && len(rest) > 1 && strings.ContainsRune("\\`*_[]()#+-.!>", rune(rest[1])):
+			literal.WriteString(html.EscapeString(rest[1:2]))
+			i += 2
+			continue
+
+		case rest[0] == '`' && !noCodeSpan:
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				literal.WriteString("<code>" + html.EscapeString(rest[1:end+1]) + "</code>")
+				i += end + 2
+				continue
+			}
+			noCodeSpan = true
+
+		case strings.HasPrefix(rest, "![") && !noLink:
+			if label, target, n, ok := parseLink(rest[1:]); ok {
+				if src, safe := safeURL(target); safe {
+					literal.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(label) + `">`)
+				} else {
+					literal.WriteString(html.EscapeString(label))
+				}
+				i += n + 1
+				continue
+			}
+			noLink = true
+
+		case rest[0] == '[' && !noLink:
+			if label, target, n, ok := parseLink(rest); ok {
+				if href, safe := safeURL(target); safe {
+					literal.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow">` + renderInline(label) + "</a>")
+				} else {
+					literal.WriteString(renderInline(label))
+				}
+				i += n
+				continue
+			}
+			noLink = true
+
+		case rest[0] == '*' || rest[0] == '_':
+			n := 1
+			for n < len(rest) && rest[n] == rest[0] {
+				n++
+			}
+
+			flush()
+			nodes = append(nodes, inlineNode{run: newDelimiterRun(text, i, n)})
+			i += n
+			continue
+		}
+
+		literal.WriteString(html.EscapeString(rest[:1]))
+		i++
+	}
+	flush()
+
+	matchEmphasis(nodes)
+
+	var out bytes.Buffer
+	for _, node := range nodes {
+		if node.run == nil {
+			out.WriteString(node.html)
+			continue
+		}
+
+		for _, tag := range node.run.closes {
+			out.WriteString(tag)
+		}
+		out.WriteString(strings.Repeat(string(node.run.delimiter), node.run.count))
+		for j := len(node.run.opens) - 1; j >= 0; j-- {
+			out.WriteString(node.run.opens[j])
+		}
+	}
+
+	return out.String()
+}
+
+// newDelimiterRun returns the run of n delimiters starting at text[i].
+// A run followed by a space can't open an emphasis and a run preceded by a space can't close one.
+// An underscore inside a word (e.g. snake_case) neither opens nor closes an emphasis.
+func newDelimiterRun(text string, i int, n int) *delimiterRun {
+	delimiter := text[i]
+	before, after := byte(' '), byte(' ')
+	if i > 0 {
+		before = text[i-1]
+	}
+	if i+n < len(text) {
+		after = text[i+n]
+	}
+
+	return &delimiterRun{
+		delimiter: delimiter,
+		count:     n,
+		canOpen:   !isSpace(after) && (delimiter != '_' || !isWordByte(before)),
+		canClose:  !isSpace(before) && (delimiter != '_' || !isWordByte(after)),
+	}
+}
+
+// matchEmphasis match the closing delimiter runs with the closest opening run of the same delimiter,
+// the runs opened in between are left unmatched so the tags are always properly nested.
+// Two delimiters on both sides make a strong emphasis, one an emphasis.
+func matchEmphasis(nodes []inlineNode) {
+	var openers []*delimiterRun
+	// opened counts the openers of each delimiter on the stack, a closer without opener doesn't search it
+	opened := map[byte]int{}
+
+	for _, node := range nodes {
+		run := node.run
+		if run == nil {
+			continue
+		}
+
+		for run.canClose && run.count > 0 && opened[run.delimiter] > 0 {
+			opener := openers[len(openers)-1]
+			if opener.delimiter != run.delimiter {
+				openers = openers[:len(openers)-1]
+				opened[opener.delimiter]--
+				continue
+			}
+
+			tag := "em"
+			width := 1
+			if opener.count >= 2 && run.count >= 2 {
+				tag = "strong"
+				width = 2
+			}
+
+			opener.opens = append(opener.opens, "<"+tag+">")
+			run.closes = append(run.closes, "</"+tag+">")
+			opener.count -= width
+			run.count -= width
+
+			if opener.count == 0 {
+				openers = openers[:len(openers)-1]
+				opened[opener.delimiter]--
+			}
+		}
+
+		if run.canOpen && run.count > 0 {
+			openers = append(openers, run)
+			opened[run.delimiter]++
+		}
+	}
+}
+
+// parseLink parse a [label](target) at the start of text
+// It returns the number of bytes read and false when there is no link.
+func parseLink(text string) (label string, target string, n int, ok bool) {
+	closing := strings.Index(text, "](")
+	if closing < 0 {
+		return
+	}
+
+	end := strings.Index(text[closing+2:], ")")
+	if end < 0 {
+		return
+	}
+
+	label = text[1:closing]
+	// An optional title follows the URL, it is ignored
+	if fields := strings.Fields(text[closing+2 : closing+2+end]); len(fields) > 0 {
+		target = fields[0]
+	}
+
+	return label, target, closing + 2 + end + 1, true
+}
+
+// safeURL check the URL can be used in a link or an image
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || raw == "" {
+		return "", false
+	}
+
+	if u.Scheme != "" && !allowedSchemes[u.Scheme] {
+		return "", false
+	}
+
+	return u.String(), true
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n'
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
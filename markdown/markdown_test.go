@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"paragraphs",
+			"first paragraph\nstill first\n\nsecond paragraph",
+			"<p>first paragraph\nstill first</p>\n<p>second paragraph</p>",
+		},
+		{
+			"headings",
+			"# Title\n### Section ###",
+			"<h1>Title</h1>\n<h3>Section</h3>",
+		},
+		{
+			"emphasis",
+			"*em* **strong** _em_ __strong__ snake_case_word 2 * 3",
+			"<p><em>em</em> <strong>strong</strong> <em>em</em> <strong>strong</strong> snake_case_word 2 * 3</p>",
+		},
+		{
+			"nested emphasis",
+			"*an **important** point*",
+			"<p><em>an <strong>important</strong> point</em></p>",
+		},
+		{
+			"overlapping emphasis",
+			"*a __b* c__ ***d** e*",
+			"<p><em>a __b</em> c__ <em><strong>d</strong> e</em></p>",
+		},
+		{
+			"unclosed emphasis",
+			"**a *b",
+			"<p>**a *b</p>",
+		},
+		{
+			"code",
+			"use `<b>` tags\n\n```go\nif a < b {\n}\n```\n\n    indented <code>",
+			"<p>use <code>&lt;b&gt;</code> tags</p>\n<pre><code class=\"language-go\">if a &lt; b {\n}\n</code></pre>\n<pre><code>indented &lt;code&gt;\n</code></pre>",
+		},
+		{
+			"lists",
+			"- one\n- two\n  continued\n\n1. first\n2. second",
+			"<ul>\n<li>one</li>\n<li>two continued</li>\n</ul>\n<ol>\n<li>first</li>\n<li>second</li>\n</ol>",
+		},
+		{
+			"blockquote and rule",
+			"> quoted\n> **text**\n\n---",
+			"<blockquote>\n<p>quoted\n<strong>text</strong></p>\n</blockquote>\n<hr>",
+		},
+		{
+			"links and images",
+			"[conduit](https://example.com/?a=1&b=2 \"title\") ![logo](/logo.png)",
+			"<p><a href=\"https://example.com/?a=1&amp;b=2\" rel=\"nofollow\">conduit</a> <img src=\"/logo.png\" alt=\"logo\"></p>",
+		},
+		{
+			"escapes",
+			`\*not em\* 1\. not a list`,
+			"<p>*not em* 1. not a list</p>",
+		},
+		{
+			"raw html is escaped",
+			"<script>alert('xss')</script>\n<img src=x onerror=alert(1)>",
+			"<p>&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;\n&lt;img src=x onerror=alert(1)&gt;</p>",
+		},
+		{
+			"unsafe links are rendered as text",
+			"[click](javascript:alert(1)) [data](DATA:text/html;base64,PHNjcmlwdD4=) ![img](vbscript:x)",
+			"<p>click) data img</p>",
+		},
+		{
+			"attributes can't be broken out of",
+			`[a](http://example.com/"onmouseover="alert(1)) ![b" onerror="alert(1)](/x.png)`,
+			"<p><a href=\"http://example.com/%22onmouseover=%22alert%281\" rel=\"nofollow\">a</a>) <img src=\"/x.png\" alt=\"b&#34; onerror=&#34;alert(1)\"></p>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.source); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	source := "# Title\n\nSome **bold** and [a link](http://example.com) &amp; <b>html</b>\n\n- item"
+	want := "Title\nSome bold and a link &amp; <b>html</b>\nitem"
+
+	if got := Text(source); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderPathologicalInputs(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{"unclosed emphasis", strings.Repeat("*a ", 100000)},
+		{"unclosed strong emphasis", strings.Repeat("**a ", 100000)},
+		{"alternating delimiters", strings.Repeat("_a *", 100000)},
+		{"unclosed code spans", strings.Repeat("` a", 100000)},
+		{"unclosed links", strings.Repeat("[a](", 100000)},
+		{"nested blockquotes", strings.Repeat(">", 100000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			Render(tt.source)
+
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Render() took %v for %d bytes", elapsed, len(tt.source))
+			}
+		})
+	}
+}
@@ -2,36 +2,49 @@ package models
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/Machiel/slugify"
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/markdown"
 	"github.com/jinzhu/gorm"
 )
 
 type ArticleStorer interface {
 	CreateArticle(*Article) error
 	DeleteArticle(*Article) error
+	PurgeArticle(*Article) error
 	GetAllArticles() *gorm.DB
+	GetAllArticlesWithStatus(string, *User) *gorm.DB
 	GetAllArticlesAuthoredBy(string, int, int) ([]Article, error)
 	GetAllArticlesFavoritedBy(string, int, int) ([]Article, error)
 	GetAllArticlesWithTag(string, int, int) ([]Article, error)
 	GetArticle(string) (*Article, error)
+	ListedArticleIDs([]int, *User) ([]int, error)
 	FavoriteArticle(*User, *Article) error
+	RefreshFavoritesCounts() error
 	UnfavoriteArticle(*User, *Article) error
 	FindUserByUsername(string) (*User, error)
 	IsFavorited(int, int) bool
-	IsFollowing(int, int) bool
 	SaveArticle(*Article) error
 	FilterAuthoredBy(*gorm.DB, interface{}) *gorm.DB
 	FilterFavoritedBy(*gorm.DB, interface{}) *gorm.DB
 	FilterByTag(*gorm.DB, interface{}) *gorm.DB
+	FilterCreatedBetween(*gorm.DB, interface{}) *gorm.DB
+	ExcludeAuthoredBy(*gorm.DB, interface{}) *gorm.DB
+	ExcludeTag(*gorm.DB, interface{}) *gorm.DB
 	Limit(*gorm.DB, interface{}) *gorm.DB
 	Offset(*gorm.DB, interface{}) *gorm.DB
+	After(*gorm.DB, interface{}) *gorm.DB
+	SortBy(*gorm.DB, interface{}) *gorm.DB
+	PublishScheduledArticles(time.Time) (int64, error)
 }
 
 // Article the article model
@@ -41,26 +54,85 @@ type Article struct {
 	Title          string
 	Description    string
 	Body           string
+	BodyHTML       string `gorm:"type:text"`
+	WordCount      int
+	ReadingTime    int
+	Excerpt        string `gorm:"type:text"`
 	User           User
 	UserID         int   `gorm:"index:index_articles_on_user_id"`
 	Tags           []Tag `gorm:"many2many:taggings;"`
 	Favorites      []Favorite
 	Comments       []Comment
 	FavoritesCount int
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
+	// CommentsCount is the number of comments out of the trash, see RefreshCommentsCounts
+	CommentsCount int
+	Status        string `gorm:"index:index_articles_on_status;default:'published'"`
+	PublishedAt   *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	// HiddenAt is when the moderators hid the article, nil when it is not hidden
+	HiddenAt  *time.Time
+	DeletedAt *time.Time `sql:"index"`
 }
 
-var (
-	errorArticleAlreadyFavorited = errors.New("This article is already in your favorites !")
-	errorArticleNotFavorited     = errors.New("Cannot remove this article from your favorites. This article is not in your favorites !")
+// Article statuses, only the published articles are listed
+// and the other ones can only be read by their author.
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
 )
 
 const (
 	defaultOffset = 0
 	defaultLimit  = 20
+
+	tagMatchAny = "any"
+	tagMatchAll = "all"
+
+	// taggedWithQuery select the id of the articles tagged with the tags matching %v
+	taggedWithQuery = "SELECT taggings.article_id FROM taggings JOIN tags ON tags.id = taggings.tag_id WHERE %v"
+)
+
+const defaultArticleSort = "newest"
+
+const (
+	// wordsPerMinute is the reading speed the reading time is estimated with
+	wordsPerMinute = 200
+	// excerptLength is the maximum number of characters of the generated excerpts
+	excerptLength = 200
+	// minDescriptionLength is the length under which the description is too short to be the excerpt
+	minDescriptionLength = 50
 )
 
+// articleSorts are the accepted values of the 'sort' query string param
+var articleSorts = map[string]sortOrder{
+	"newest":    {"articles.created_at", true, true},
+	"oldest":    {"articles.created_at", false, true},
+	"favorited": {"articles.favorites_count", true, true},
+	"commented": {"articles.comments_count", true, true},
+	"updated":   {"articles.updated_at", true, true},
+}
+
+// ReservedSlugs are the slugs of the routes under /api/articles (e.g. /api/articles/feed),
+// an article with one of them could not be fetched.
+var ReservedSlugs = []string{"feed", "search", "trending"}
+
+// articleStatuses are the accepted article statuses
+var articleStatuses = []string{StatusDraft, StatusScheduled, StatusPublished, StatusArchived}
+
+// dateLayouts are the accepted formats for the createdAfter/createdBefore filters
+var dateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// MaxLimit is the maximum number of rows a listing can return,
+// it can be changed at startup to fit the deployment.
+var MaxLimit = 100
+
+// MaxBodyLength is the maximum number of characters of the article and comment bodies,
+// it can be changed at startup to fit the deployment.
+var MaxBodyLength = 65536
+
 // NewArticle returns a new Article instance.
 func NewArticle(title string, description string, body string, user *User) *Article {
 	return &Article{
@@ -68,6 +140,7 @@ func NewArticle(title string, description string, body string, user *User) *Arti
 		Description: description,
 		Body:        body,
 		User:        *user,
+		Status:      StatusPublished,
 	}
 }
 
@@ -79,6 +152,9 @@ func (a *Article) IsValid() (bool, ValidationErrors) {
 	if a.Title == "" {
 		errs["title"] = []string{EMPTY_MSG}
 		valid = false
+	} else if slug := slugify.Slugify(a.Title); contains(ReservedSlugs, slug) {
+		errs["title"] = []string{fmt.Sprintf(RESERVED_MSG, slug)}
+		valid = false
 	}
 
 	if a.Description == "" {
@@ -89,27 +165,179 @@ func (a *Article) IsValid() (bool, ValidationErrors) {
 	if a.Body == "" {
 		errs["body"] = []string{EMPTY_MSG}
 		valid = false
+	} else if utf8.RuneCountInString(a.Body) > MaxBodyLength {
+		errs["body"] = []string{fmt.Sprintf(TOO_LONG_MSG, MaxBodyLength)}
+		valid = false
+	}
+
+	if !isArticleStatus(a.Status) {
+		errs["status"] = []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(articleStatuses, ", "))}
+		valid = false
+	}
+
+	if a.Status == StatusScheduled && a.PublishedAt == nil {
+		errs["publishedAt"] = []string{EMPTY_MSG}
+		valid = false
 	}
 
 	return valid, errs
 }
 
+// IsPublished check if the article is published
+func (a *Article) IsPublished() bool {
+	return a.Status == StatusPublished
+}
+
+// IsHidden check if the moderators hid the article
+func (a *Article) IsHidden() bool {
+	return a.HiddenAt != nil
+}
+
+// IsVisibleTo check if the given user can read the article,
+// unpublished articles are only visible to their author
+// and hidden ones to their author and the moderators.
+func (a *Article) IsVisibleTo(user *User) bool {
+	if user.ID != 0 && a.UserID == user.ID {
+		return true
+	}
+
+	if a.IsHidden() {
+		return user.IsModerator()
+	}
+
+	return a.IsPublished()
+}
+
 // IsOwnedBy check if the article is owned by the given username
 func (a *Article) IsOwnedBy(username string) bool {
 	return a.User.Username == username
 }
 
-// CreateArticle persist a new article
+// RenderedBody returns the body rendered to sanitized HTML, it is rendered on save
+// so only the articles saved before the rendering existed are rendered on the fly.
+func (a *Article) RenderedBody() string {
+	if a.BodyHTML == "" && a.Body != "" {
+		return markdown.Render(a.Body)
+	}
+
+	return a.BodyHTML
+}
+
+// Cursor returns the cursor pointing to this article in a listing ordered by the
+// given sort, or nil when the sort can't be paginated with cursors.
+func (a *Article) Cursor(sort string) *Cursor {
+	switch sort {
+	case "newest", "oldest":
+		return NewCursor(sort, a.CreatedAt, a.ID)
+	case "favorited":
+		return NewCursor(sort, a.FavoritesCount, a.ID)
+	case "commented":
+		return NewCursor(sort, a.CommentsCount, a.ID)
+	case "updated":
+		return NewCursor(sort, a.UpdatedAt, a.ID)
+	}
+
+	return nil
+}
+
+// CreateArticle persist a new article along with its first revision
 func (db *DB) CreateArticle(article *Article) (err error) {
-	err = db.Create(&article).Error
+	tx := db.Begin()
+
+	if err = tx.Create(&article).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = tx.Create(NewRevision(article, &article.User, 1, revisionFields)).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	var tagIDs []uint
+	for _, t := range article.Tags {
+		tagIDs = append(tagIDs, t.ID)
+	}
+
+	if err = refreshTaggingsCount(tx, tagIDs); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit().Error
 	return
 }
 
-// DeleteArticle delete an article
-// WARNING You must provide a primary key in the struct orherwise
-// gorm will delete all article models.
+// DeleteArticle move an article to the trash, it is purged by PurgeTrash
+// once the retention is over. A primary key must be provided.
 func (db *DB) DeleteArticle(article *Article) (err error) {
-	err = db.Delete(&article).Error
+	if article.ID == 0 {
+		return errorMissingPrimaryKey
+	}
+
+	tx := db.Begin()
+
+	tagIDs, err := tagIDsOf(tx, article.ID)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = tx.Delete(&article).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = refreshTaggingsCount(tx, tagIDs); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit().Error
+	return
+}
+
+// PurgeArticle permanently delete an article, trashed or not, along with its
+// comments, favorites, taggings and revisions. A primary key must be provided.
+func (db *DB) PurgeArticle(article *Article) (err error) {
+	if article.ID == 0 {
+		return errorMissingPrimaryKey
+	}
+
+	tx := db.Begin()
+
+	tagIDs, err := tagIDsOf(tx, article.ID)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	// The dependent rows are deleted without their callbacks,
+	// they would update the article being deleted.
+	err = tx.Exec("DELETE FROM comment_versions WHERE comment_id IN (SELECT id FROM comments WHERE article_id = ?)", article.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	for _, table := range []string{"comments", "favorites", "taggings", "revisions", "reports", "notifications"} {
+		if err = tx.Exec("DELETE FROM "+table+" WHERE article_id = ?", article.ID).Error; err != nil {
+			tx.Rollback()
+			return
+		}
+	}
+
+	if err = tx.Unscoped().Delete(&article).Error; err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = refreshTaggingsCount(tx, tagIDs); err != nil {
+		tx.Rollback()
+		return
+	}
+
+	err = tx.Commit().Error
 	return
 }
 
@@ -119,19 +347,92 @@ func (db *DB) SaveArticle(article *Article) (err error) {
 	return
 }
 
-// GetArticle retrieve an article by it slug
+// GetArticle retrieve an article by it slug, whatever its status
 func (db *DB) GetArticle(slug string) (*Article, error) {
 	var article Article
-	err := db.DB.Scopes(defaultArticleScope).First(&article, "slug = ?", slug).Error
+	err := db.DB.Scopes(articleScope).First(&article, "slug = ?", slug).Error
 	return &article, err
 }
 
-// GetAllArticles return a scope query to fetch all articles.
+// ListedArticleIDs keep the ids of the listed articles, see GetAllArticles, in the same order.
+// The articles of the users hidden from u are left out.
+func (db *DB) ListedArticleIDs(ids []int, u *User) ([]int, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var listedIDs []int
+	query := db.Model(&Article{}).Scopes(listedArticleScope).Where("articles.id IN (?)", ids)
+
+	if err := db.ExcludeHiddenAuthors(query, u).Pluck("articles.id", &listedIDs).Error; err != nil {
+		return nil, err
+	}
+
+	listed := map[int]bool{}
+	for _, id := range listedIDs {
+		listed[id] = true
+	}
+
+	listedIDs = listedIDs[:0]
+	for _, id := range ids {
+		if listed[id] {
+			listedIDs = append(listedIDs, id)
+		}
+	}
+
+	return listedIDs, nil
+}
+
+// GetArticlesByID retrieve the articles with the given ids, in the same order
+func (db *DB) GetArticlesByID(ids []int) ([]Article, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var articles []Article
+	if err := db.Scopes(defaultArticleScope).Where("articles.id IN (?)", ids).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	byID := map[int]Article{}
+	for _, a := range articles {
+		byID[a.ID] = a
+	}
+
+	articles = articles[:0]
+	for _, id := range ids {
+		if a, ok := byID[id]; ok {
+			articles = append(articles, a)
+		}
+	}
+
+	return articles, nil
+}
+
+// GetAllArticles return a scope query to fetch all published articles.
 // You must call Find at the end to perform the query.
 func (db *DB) GetAllArticles() *gorm.DB {
 	return db.Scopes(defaultArticleScope)
 }
 
+// GetAllArticlesWithStatus return a scope query to fetch all articles of the given author with the given status.
+// You must call Find at the end to perform the query.
+func (db *DB) GetAllArticlesWithStatus(status string, author *User) *gorm.DB {
+	return db.Scopes(articleScope).
+		Where("articles.status = ? AND articles.user_id = ?", status, author.ID)
+}
+
+// PublishScheduledArticles publish the scheduled articles whose publication time is before now
+// It returns the number of published articles.
+func (db *DB) PublishScheduledArticles(now time.Time) (int64, error) {
+	// UpdateColumn skips the callbacks, they expect an article to be loaded.
+	query := db.Model(&Article{}).
+		Where("status = ? AND published_at <= ?", StatusScheduled, now).
+		UpdateColumn("status", StatusPublished)
+
+	return query.RowsAffected, query.Error
+}
+
 // GetAllArticlesWithTag get all articles containings the given tag name.
 func (db *DB) GetAllArticlesWithTag(tagName string, limit int, offset int) (articles []Article, err error) {
 	scopedQuery := db.FilterByTag(db.Scopes(defaultArticleScope), tagName)
@@ -168,12 +469,6 @@ func (db *DB) IsFavorited(userID int, articleID int) bool {
 	return true
 }
 
-// IsFollowing check if the given userIDFrom follows userIDTo
-func (db *DB) IsFollowing(userIDFrom int, userIDTo int) bool {
-	// TODO
-	return false
-}
-
 // FindUserByUsername find a user by its username
 func (db *DB) FindUserByUsername(username string) (*User, error) {
 	var user User
@@ -182,72 +477,170 @@ func (db *DB) FindUserByUsername(username string) (*User, error) {
 }
 
 // FavoriteArticle add the article to the favorites of the given user
+// It is idempotent: when the user already favorited it the article is only reloaded.
 func (db *DB) FavoriteArticle(u *User, a *Article) error {
-	var err error
-	f := Favorite{UserID: u.ID, ArticleID: a.ID}
+	tx := db.Begin()
 
-	if !db.IsFavorited(u.ID, a.ID) {
-		err = db.Model(&a).Association("Favorites").Append(f).Error
-		err = db.First(&a).Error
-	} else {
-		err = errorArticleAlreadyFavorited
+	query := tx.Exec(insertFavoriteQuery, u.ID, a.ID, time.Now(), u.ID, a.ID)
+	if query.Error != nil {
+		tx.Rollback()
+		return query.Error
+	}
+
+	// The favorite is inserted without the model, its callback is run by hand.
+	if err := incrementFavoritesCount(tx, a.ID, query.RowsAffected); err != nil {
+		tx.Rollback()
+		return err
 	}
 
-	return err
+	notification, notified := &Notification{UserID: a.UserID, ActorID: u.ID, Kind: NotifyFavorite, ArticleID: a.ID}, false
+	if query.RowsAffected > 0 {
+		var err error
+		if notified, err = notify(tx, notification); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	db.notified(notification, notified)
+
+	// Reload the article reference, to update the favorites_count
+	return db.First(&a).Error
 }
 
 // UnfavoriteArticle remove the article to the favorites of the given user
+// It is idempotent: when the user did not favorite it the article is only reloaded.
 func (db *DB) UnfavoriteArticle(u *User, a *Article) error {
-	var err error
-	f := Favorite{}
-
-	if !db.First(&f, Favorite{UserID: u.ID, ArticleID: a.ID}).RecordNotFound() {
-		// We can't use the association mode because AfterCreate callbacks on favorite
-		// will not be called and favorties_count will not be decremented
-		// so we need to use Delete() directly.
-		err = db.Delete(&f).Error
-		// Reload the article reference, to update the favorites_count
-		err = db.First(&a).Error
-	} else {
-		err = errorArticleNotFavorited
+	tx := db.Begin()
+
+	// The favorites are deleted in bulk so the callback can't see their article,
+	// the favorites count is decremented by hand.
+	query := tx.Where("user_id = ? AND article_id = ?", u.ID, a.ID).Delete(Favorite{})
+	if query.Error != nil {
+		tx.Rollback()
+		return query.Error
+	}
+
+	if err := incrementFavoritesCount(tx, a.ID, -query.RowsAffected); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
 	}
 
-	return err
+	// Reload the article reference, to update the favorites_count
+	return db.First(&a).Error
 }
 
 // Callbacks
 
 // BeforeCreate gorm callback
-// Titile slugyfication
+// Titile slugyfication, the reserved slugs are rejected, publication time, body rendering and summary
 func (a *Article) BeforeCreate() (err error) {
 	a.Slug = slugify.Slugify(a.Title)
+	if contains(ReservedSlugs, a.Slug) {
+		return fmt.Errorf(RESERVED_MSG, a.Slug)
+	}
+	a.BodyHTML = markdown.Render(a.Body)
+	a.summarize()
+	a.stampPublication()
 	return
 }
 
 // BeforeUpdate gorm callback
-// Titile slugyfication
+// Titile slugyfication, the reserved slugs are rejected, publication time, body rendering and summary
 func (a *Article) BeforeUpdate() (err error) {
 	a.Slug = slugify.Slugify(a.Title)
+	if contains(ReservedSlugs, a.Slug) {
+		return fmt.Errorf(RESERVED_MSG, a.Slug)
+	}
+	a.BodyHTML = markdown.Render(a.Body)
+	a.summarize()
+	a.stampPublication()
 	return
 }
 
-// FilterByTag filtering article by tag name
+// summarize compute the word count and the reading time of the body, and the excerpt:
+// the description, or the beginning of the body when the description is too short.
+func (a *Article) summarize() {
+	text := markdown.Text(a.Body)
+
+	a.WordCount = countWords(text)
+	a.ReadingTime = int(math.Ceil(float64(a.WordCount) / wordsPerMinute))
+
+	if len([]rune(strings.TrimSpace(a.Description))) >= minDescriptionLength {
+		a.Excerpt = a.Description
+	} else {
+		a.Excerpt = excerpt(text, excerptLength)
+	}
+}
+
+// stampPublication set the publication time of newly published articles
+// and clear the one of drafts.
+func (a *Article) stampPublication() {
+	switch a.Status {
+	case StatusPublished:
+		if a.PublishedAt == nil {
+			now := time.Now()
+			a.PublishedAt = &now
+		}
+	case StatusDraft:
+		a.PublishedAt = nil
+	}
+}
+
+// FilterByTag filtering article by tag name(s), value argument can be string|[]string|url.Values
+// If a url.Values provided, it must contains a query string 'tag' param name, articles
+// must then have every given tags when the 'tagMatch' param is 'all' and any of them otherwise.
 func (DB) FilterByTag(db *gorm.DB, value interface{}) *gorm.DB {
 	var whereClause string
 	var args interface{}
 	var skip = true
+	var matchAll = false
 
 	switch value.(type) {
 	case url.Values:
 		whereClause, args, skip = buildWhereClause("tags.name", "tag", value)
+		matchAll = value.(url.Values).Get("tagMatch") == tagMatchAll
 	default:
 		whereClause, args, skip = buildWhereClause("tags.name", value)
 	}
 
 	if !skip {
-		return db.Joins("JOIN taggings ON taggings.article_id = articles.id").
-			Joins("JOIN tags ON tags.id = taggings.tag_id").
-			Where(whereClause, args)
+		subQuery := fmt.Sprintf(taggedWithQuery, whereClause)
+
+		if matchAll {
+			subQuery += fmt.Sprintf(" GROUP BY taggings.article_id HAVING COUNT(DISTINCT tags.id) = %d", countArgs(args))
+		}
+
+		return db.Where("articles.id IN ("+subQuery+")", args)
+	}
+
+	return db
+}
+
+// ExcludeTag filter out articles tagged with tag name(s), value argument can be string|[]string|url.Values
+// If a url.Values provided, it must contains a query string '-tag' param name.
+func (DB) ExcludeTag(db *gorm.DB, value interface{}) *gorm.DB {
+	var whereClause string
+	var args interface{}
+	var skip = true
+
+	switch value.(type) {
+	case url.Values:
+		whereClause, args, skip = buildWhereClause("tags.name", "-tag", value)
+	default:
+		whereClause, args, skip = buildWhereClause("tags.name", value)
+	}
+
+	if !skip {
+		return db.Where("articles.id NOT IN ("+fmt.Sprintf(taggedWithQuery, whereClause)+")", args)
 	}
 
 	return db
@@ -274,6 +667,27 @@ func (DB) FilterAuthoredBy(db *gorm.DB, value interface{}) *gorm.DB {
 	return db
 }
 
+// ExcludeAuthoredBy filter out articles authored by user(s) username, value argument can be string|[]string|url.Values
+// If a url.Values provided, it must contains a query string '-author' param name.
+func (DB) ExcludeAuthoredBy(db *gorm.DB, value interface{}) *gorm.DB {
+	var whereClause string
+	var args interface{}
+	var skip = true
+
+	switch value.(type) {
+	case url.Values:
+		whereClause, args, skip = buildWhereClause("users.username", "-author", value)
+	default:
+		whereClause, args, skip = buildWhereClause("users.username", value)
+	}
+
+	if !skip {
+		return db.Where("articles.user_id NOT IN (SELECT users.id FROM users WHERE "+whereClause+")", args)
+	}
+
+	return db
+}
+
 // FilterFavoritedBy filter articles favorited by user(s) username, value argument can be string|[]string|url.Values
 // If a url.Values provided, it must contains a query string 'favorited' param name.
 func (DB) FilterFavoritedBy(db *gorm.DB, value interface{}) *gorm.DB {
@@ -299,42 +713,111 @@ func (DB) FilterFavoritedBy(db *gorm.DB, value interface{}) *gorm.DB {
 			return db
 		}
 
-		return db.Joins("JOIN favorites ON favorites.article_id = articles.id").
-			Where("favorites.user_id IN (?)", ids)
+		// A sub query is used rather than a join so an article favorited
+		// by several of the given users is only returned once.
+		return db.Where("articles.id IN (SELECT favorites.article_id FROM favorites WHERE favorites.user_id IN (?))", ids)
 	}
 
 	return db
 }
 
-// Offset set the wanted offset (defaulf: 0) to an existing *gorm.DB instance.
-func (DB) Offset(db *gorm.DB, offset interface{}) *gorm.DB {
-	var offsetValue = defaultOffset
+// FilterCreatedBetween filter articles created after and/or before the given dates, value argument must be an url.Values
+// containing a query string 'createdAfter' and/or 'createdBefore' param names formatted as RFC3339 or YYYY-MM-DD.
+// Invalid dates are ignored, see ValidateFilters to report them to the client.
+func (DB) FilterCreatedBetween(db *gorm.DB, value interface{}) *gorm.DB {
+	queryParams, ok := value.(url.Values)
+	if !ok {
+		return db
+	}
 
-	switch offset.(type) {
-	case url.Values:
-		queryParams := offset.(url.Values)
-		v := queryParams.Get("offset")
+	if after, err := parseDate(queryParams.Get("createdAfter")); err == nil {
+		db = db.Where("articles.created_at > ?", after)
+	}
 
-		if v != "" {
-			intVal, err := strconv.Atoi(v)
-			if err != nil {
-				offsetValue = defaultOffset
-			} else {
-				offsetValue = intVal
+	if before, err := parseDate(queryParams.Get("createdBefore")); err == nil {
+		db = db.Where("articles.created_at < ?", before)
+	}
+
+	return db
+}
+
+// ValidateFilters check the filters query string params that expect a specific format
+// It returns nil when they are absent or valid.
+func ValidateFilters(queryParams url.Values) ValidationErrors {
+	var errs = ValidationErrors{}
+
+	for _, key := range []string{"createdAfter", "createdBefore"} {
+		if v := queryParams.Get(key); v != "" {
+			if _, err := parseDate(v); err != nil {
+				errs[key] = []string{INVALID_DATE_MSG}
 			}
-		} else {
-			offsetValue = defaultOffset
 		}
 	}
 
-	return db.Offset(offsetValue)
+	if v := queryParams.Get("status"); v != "" && !isArticleStatus(v) {
+		errs["status"] = []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(articleStatuses, ", "))}
+	}
+
+	if v := queryParams.Get("tagMatch"); v != "" && v != tagMatchAny && v != tagMatchAll {
+		errs["tagMatch"] = []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join([]string{tagMatchAny, tagMatchAll}, ", "))}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Offset set the wanted offset (defaulf: 0) to an existing *gorm.DB instance.
+// Invalid or negative offsets fall back to the default, see ValidatePagination
+// to report them to the client instead.
+func (DB) Offset(db *gorm.DB, offset interface{}) *gorm.DB {
+	return db.Offset(PageOffset(offset))
 }
 
 // Limit set the number of max articles to fetch (defaulf: 20) to an existing *gorm.DB instance.
 func (DB) Limit(db *gorm.DB, limit interface{}) *gorm.DB {
+	return db.Limit(PageSize(limit))
+}
+
+// After restrict the articles to the ones listed after the given cursor,
+// value argument can be *Cursor|string|url.Values
+// If a url.Values provided, it must contains a query string 'cursor' param name.
+func (DB) After(db *gorm.DB, value interface{}) *gorm.DB {
+	return keyset(db, "articles", articleSorts, value)
+}
+
+// SortBy order the articles (default: newest), value argument can be string|url.Values
+// If a url.Values provided, it must contains a query string 'sort' param name.
+// Unknown sorts are ignored, see ValidateArticlesSort to report them to the client.
+func (DB) SortBy(db *gorm.DB, value interface{}) *gorm.DB {
+	if order, ok := articleSorts[ArticlesSort(value)]; ok {
+		return orderBy(db, "articles", order)
+	}
+
+	return db
+}
+
+// ArticlesSort returns the name of the sort found in value (default: newest)
+func ArticlesSort(value interface{}) string {
+	return sortFrom(value, defaultArticleSort)
+}
+
+// ValidateArticlesSort check the 'sort' query string param of the articles listings
+// It returns nil when it is absent or valid.
+func ValidateArticlesSort(queryParams url.Values) ValidationErrors {
+	return validateSort(queryParams, articleSorts, defaultArticleSort)
+}
+
+// PageSize returns the number of max rows to fetch (defaulf: 20) for the given limit
+// Invalid limits fall back to the default and too large ones are clamped to MaxLimit.
+func PageSize(limit interface{}) int {
 	var limitValue = defaultLimit
 
 	switch limit.(type) {
+	case int:
+		limitValue = limit.(int)
 	case url.Values:
 		queryParams := limit.(url.Values)
 		v := queryParams.Get("limit")
@@ -351,16 +834,92 @@ func (DB) Limit(db *gorm.DB, limit interface{}) *gorm.DB {
 		}
 	}
 
-	return db.Limit(limitValue)
+	if limitValue < 1 {
+		limitValue = defaultLimit
+	}
+
+	if limitValue > MaxLimit {
+		limitValue = MaxLimit
+	}
+
+	return limitValue
+}
+
+// PageOffset returns the number of rows to skip (defaulf: 0) for the given offset
+// Invalid or negative offsets fall back to the default.
+func PageOffset(offset interface{}) int {
+	var offsetValue = defaultOffset
+
+	switch offset.(type) {
+	case int:
+		offsetValue = offset.(int)
+	case url.Values:
+		queryParams := offset.(url.Values)
+		v := queryParams.Get("offset")
+
+		if v != "" {
+			intVal, err := strconv.Atoi(v)
+			if err != nil {
+				offsetValue = defaultOffset
+			} else {
+				offsetValue = intVal
+			}
+		} else {
+			offsetValue = defaultOffset
+		}
+	}
+
+	if offsetValue < 0 {
+		offsetValue = defaultOffset
+	}
+
+	return offsetValue
+}
+
+// ValidatePagination check the limit and offset query string params
+// It returns nil when they are absent or valid.
+func ValidatePagination(queryParams url.Values) ValidationErrors {
+	var errs = ValidationErrors{}
+
+	if v := queryParams.Get("limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err != nil || limit < 1 || limit > MaxLimit {
+			errs["limit"] = []string{fmt.Sprintf(NOT_IN_RANGE_MSG, 1, MaxLimit)}
+		}
+	}
+
+	if v := queryParams.Get("offset"); v != "" {
+		if offset, err := strconv.Atoi(v); err != nil || offset < 0 {
+			errs["offset"] = []string{NEGATIVE_MSG}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
 }
 
 ///////////////////////////////////////////////////////////////////////////////
 // Scopes															 		 //
 ///////////////////////////////////////////////////////////////////////////////
 
-// Order articles by created_at DESC eager loading Tags and User
+// Published articles not hidden by the moderators ordered by created_at DESC eager loading Tags and User
 func defaultArticleScope(db *gorm.DB) *gorm.DB {
+	return db.Scopes(articleScope, listedArticleScope)
+}
+
+// Keep the published articles which are not hidden, the ones listed to every user
+func listedArticleScope(db *gorm.DB) *gorm.DB {
+	return db.Where("articles.status = ?", StatusPublished).
+		Where("articles.hidden_at IS NULL")
+}
+
+// Order articles by created_at DESC eager loading Tags and User
+// The id is used as a tie breaker to keep a stable order for cursors
+func articleScope(db *gorm.DB) *gorm.DB {
 	return db.Order("articles.created_at desc").
+		Order("articles.id desc").
 		Preload("Tags").
 		Preload("User")
 }
@@ -405,12 +964,16 @@ func buildWhereClause(field string, vars ...interface{}) (string, interface{}, b
 			args = value
 		}
 	case url.Values:
-		queryParams := value.(url.Values)
-		v := queryParams.Get(key)
-		skip = (v == "")
-		if !skip {
+		// Each param can be repeated and/or contains comma separated values
+		// e.g. ?tag=go&tag=gin or ?author=john,jane
+		values := splitValues(value.(url.Values)[key])
+		skip = (len(values) == 0)
+		if !skip && len(values) == 1 {
 			whereClause = "%v = ?"
-			args = v
+			args = values[0]
+		} else if !skip {
+			whereClause = "%v IN (?)"
+			args = values
 		}
 	default:
 		skip = true
@@ -423,3 +986,108 @@ func buildWhereClause(field string, vars ...interface{}) (string, interface{}, b
 	return fmt.Sprintf(whereClause, field), args, skip
 
 }
+
+// isArticleStatus check if status is one of the articleStatuses
+func isArticleStatus(status string) bool {
+	for _, s := range articleStatuses {
+		if s == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitValues split the comma separated values of a query string param,
+// empty and repeated values are dropped so they are only counted once.
+func splitValues(params []string) []string {
+	var values []string
+	seen := map[string]bool{}
+
+	for _, param := range params {
+		for _, v := range strings.Split(param, ",") {
+			if v = strings.TrimSpace(v); v != "" && !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+
+	return values
+}
+
+// countArgs returns the number of values of a where clause argument
+func countArgs(args interface{}) int {
+	if v := reflect.ValueOf(args); v.Kind() == reflect.Slice {
+		return v.Len()
+	}
+
+	return 1
+}
+
+// parseDate parse a date formatted with one of the dateLayouts
+func parseDate(value string) (t time.Time, err error) {
+	for _, layout := range dateLayouts {
+		if t, err = time.Parse(layout, value); err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+// countWords count the words of text, the punctuation alone is not a word
+func countWords(text string) (count int) {
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+
+	return
+}
+
+// excerpt returns the beginning of text up to length characters,
+// cut at the end of a word and followed by an ellipsis when text is longer.
+func excerpt(text string, length int) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	if len(runes) <= length {
+		return string(runes)
+	}
+
+	cut := length
+	for cut > 0 && !unicode.IsSpace(runes[cut]) {
+		cut--
+	}
+
+	if cut == 0 {
+		cut = length
+	}
+
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsPunct) + "…"
+}
+
+// backfillSummaries render and summarize the articles saved before the rendering and summaries existed
+func backfillSummaries(db *gorm.DB) error {
+	var articles []Article
+	if err := db.Unscoped().Where("word_count = 0 AND body <> ''").Find(&articles).Error; err != nil {
+		return err
+	}
+
+	for i := range articles {
+		a := &articles[i]
+		a.summarize()
+		err := db.Unscoped().Model(a).UpdateColumns(map[string]interface{}{
+			"body_html":    markdown.Render(a.Body),
+			"word_count":   a.WordCount,
+			"reading_time": a.ReadingTime,
+			"excerpt":      a.Excerpt,
+		}).Error
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
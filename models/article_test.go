@@ -0,0 +1,58 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestArticleSummarize(t *testing.T) {
+	longBody := strings.Repeat("word ", 450)
+	longDescription := "A description long enough to be used as the excerpt"
+
+	tests := []struct {
+		name            string
+		description     string
+		body            string
+		wantWordCount   int
+		wantReadingTime int
+		wantExcerpt     string
+	}{
+		{
+			"empty body",
+			"desc", "",
+			0, 0, "",
+		},
+		{
+			"markdown formatting is not counted",
+			"desc", "# Title\n\nSome **bold** text - and [a link](http://example.com)",
+			7, 1, "Title Some bold text - and a link",
+		},
+		{
+			"reading time rounded up",
+			longDescription, longBody,
+			450, 3, longDescription,
+		},
+		{
+			"excerpt cut at a word",
+			"desc", longBody,
+			450, 3, strings.TrimSpace(strings.Repeat("word ", 40)) + "…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Article{Description: tt.description, Body: tt.body}
+			a.summarize()
+
+			if a.WordCount != tt.wantWordCount {
+				t.Errorf("summarize() WordCount = %v, want %v", a.WordCount, tt.wantWordCount)
+			}
+			if a.ReadingTime != tt.wantReadingTime {
+				t.Errorf("summarize() ReadingTime = %v, want %v", a.ReadingTime, tt.wantReadingTime)
+			}
+			if a.Excerpt != tt.wantExcerpt {
+				t.Errorf("summarize() Excerpt = %q, want %q", a.Excerpt, tt.wantExcerpt)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type BlockStorer interface {
+	BlockUser(*User, *User) error
+	UnblockUser(*User, *User) error
+	MuteUser(*User, *User) error
+	UnmuteUser(*User, *User) error
+	IsBlocking(int, int) bool
+	IsMuting(int, int) bool
+	GetHiddenUserIDs(*User) ([]int, error)
+	ExcludeHiddenAuthors(*gorm.DB, *User) *gorm.DB
+	ExcludeHiddenCommenters(*gorm.DB, *User) *gorm.DB
+}
+
+// Block is a user blocking or muting another one, the target.
+// Their content is hidden from the user either way, a blocked target can't
+// interact with the user anymore while a muted one isn't told anything.
+type Block struct {
+	ID       int
+	UserID   int `gorm:"unique_index:index_blocks_on_user_id_and_target_id"`
+	TargetID int `gorm:"unique_index:index_blocks_on_user_id_and_target_id;index:index_blocks_on_target_id"`
+	Kind     string
+	// CreatedAt is when the user blocked or muted the target
+	CreatedAt time.Time
+}
+
+// Kinds of blocks, blocking supersedes muting
+const (
+	KindBlock = "block"
+	KindMute  = "mute"
+)
+
+// hiddenUsersQuery select the users whose content is hidden from a user
+const hiddenUsersQuery = "SELECT blocks.target_id FROM blocks WHERE blocks.user_id = ?"
+
+// BlockUser make the user block the target, they stop following each other.
+// It is idempotent and turns a mute into a block.
+func (db *DB) BlockUser(u *User, target *User) error {
+	tx := db.Begin()
+
+	block := Block{UserID: u.ID, TargetID: target.ID}
+	err := tx.Where(block).Assign(Block{Kind: KindBlock}).FirstOrCreate(&block).Error
+
+	if err == nil {
+		err = tx.Where("(follower_id = ? AND followed_id = ?) OR (follower_id = ? AND followed_id = ?)", u.ID, target.ID, target.ID, u.ID).
+			Delete(Follow{}).Error
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// UnblockUser make the user stop blocking the target
+// It is idempotent and leaves a mute as it is.
+func (db *DB) UnblockUser(u *User, target *User) error {
+	return db.unblock(u, target, KindBlock)
+}
+
+// MuteUser make the user mute the target
+// It is idempotent and leaves a block as it is.
+func (db *DB) MuteUser(u *User, target *User) error {
+	block := Block{UserID: u.ID, TargetID: target.ID}
+	return db.Where(block).Attrs(Block{Kind: KindMute}).FirstOrCreate(&block).Error
+}
+
+// UnmuteUser make the user stop muting the target
+// It is idempotent and leaves a block as it is.
+func (db *DB) UnmuteUser(u *User, target *User) error {
+	return db.unblock(u, target, KindMute)
+}
+
+// IsBlocking check if the given userID blocks targetID
+func (db *DB) IsBlocking(userID int, targetID int) bool {
+	return db.hasBlock(userID, targetID, KindBlock)
+}
+
+// IsMuting check if the given userID mutes targetID
+func (db *DB) IsMuting(userID int, targetID int) bool {
+	return db.hasBlock(userID, targetID, KindMute)
+}
+
+// GetHiddenUserIDs get the ids of the users the given user blocks or mutes
+func (db *DB) GetHiddenUserIDs(u *User) ([]int, error) {
+	var ids []int
+	err := db.Model(&Block{}).Where("user_id = ?", u.ID).Pluck("target_id", &ids).Error
+	return ids, err
+}
+
+// ExcludeHiddenAuthors filter out the articles authored by the users the given user blocks or mutes
+func (DB) ExcludeHiddenAuthors(db *gorm.DB, u *User) *gorm.DB {
+	if u.ID == 0 {
+		return db
+	}
+
+	return db.Where("articles.user_id NOT IN ("+hiddenUsersQuery+")", u.ID)
+}
+
+// ExcludeHiddenCommenters filter out the comments written by the users the given user
+// blocks or mutes, the ones with replies are kept so the replies are never orphaned.
+func (DB) ExcludeHiddenCommenters(db *gorm.DB, u *User) *gorm.DB {
+	if u.ID == 0 {
+		return db
+	}
+
+	return db.Where("comments.user_id NOT IN ("+hiddenUsersQuery+") OR "+hasRepliesQuery, u.ID)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) unblock(u *User, target *User, kind string) error {
+	return db.Where("user_id = ? AND target_id = ? AND kind = ?", u.ID, target.ID, kind).Delete(Block{}).Error
+}
+
+func (db *DB) hasBlock(userID int, targetID int, kind string) bool {
+	var count int
+	db.Model(&Block{}).
+		Where("user_id = ? AND target_id = ? AND kind = ?", userID, targetID, kind).
+		Count(&count)
+
+	return count > 0
+}
@@ -2,38 +2,92 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"time"
+	"unicode/utf8"
+
+	"github.com/guillaumemaka/realworld-starter-kit-go-gin/markdown"
+	"github.com/jinzhu/gorm"
 )
 
 // Comment is the representation of a comment
 type Comment struct {
 	ID        int
-	Body      string `gorm:"type:text`
+	Body      string `gorm:"type:text"`
+	BodyHTML  string `gorm:"type:text"`
 	Article   Article
 	ArticleID int `gorm:"index:index_comments_on_article_id"`
 	User      User
 	UserID    int `gorm:"index:index_comments_on_user_id"`
+	// ParentID is the comment this one replies to, nil for the top level comments
+	ParentID *int `gorm:"index:index_comments_on_parent_id"`
+	// Depth is the number of parents of the comment
+	Depth     int
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// EditedAt is when the body was last edited, nil when it never was
+	EditedAt *time.Time
+	// HiddenAt is when the moderators hid the comment, nil when it is not hidden
+	HiddenAt  *time.Time
+	DeletedAt *time.Time `sql:"index"`
 }
 
 var (
 	errorCommentBodyIsEmpty = errors.New(EMPTY_MSG)
 )
 
+// hasRepliesQuery check if a comment has replies, deleted or not
+const hasRepliesQuery = "EXISTS (SELECT 1 FROM comments AS replies WHERE replies.parent_id = comments.id)"
+
+const defaultCommentSort = "oldest"
+
+// countedCommentsQuery keep the comments out of the trash and not hidden, the placeholders
+// listed for the replies of the others are not counted.
+const countedCommentsQuery = "comments.deleted_at IS NULL AND comments.hidden_at IS NULL"
+
+// commentsCountQuery set the comments count of the articles to their number of counted comments
+const commentsCountQuery = `UPDATE articles SET comments_count = (SELECT COUNT(*) FROM comments
+	WHERE comments.article_id = articles.id AND ` + countedCommentsQuery + `)`
+
+// DeletedCommentBody replace the body of the deleted comments listed for their replies
+const DeletedCommentBody = "[deleted]"
+
+// HiddenCommentBody replace the body of the hidden comments listed for their replies
+const HiddenCommentBody = "[hidden]"
+
+// listedCommentsQuery keep the comments out of the trash and not hidden,
+// along with the ones with replies so the replies are never orphaned.
+const listedCommentsQuery = "(" + countedCommentsQuery + ") OR " + hasRepliesQuery
+
+// MaxCommentDepth is how deep the replies can be nested,
+// it can be changed at startup to fit the deployment.
+var MaxCommentDepth = 5
+
+// commentSorts are the accepted values of the 'sort' query string param
+var commentSorts = map[string]sortOrder{
+	"oldest": {"comments.created_at", false, true},
+	"newest": {"comments.created_at", true, true},
+}
+
 type CommentStorer interface {
 	CreateComment(*Comment) error
 	DeleteComment(*Comment) error
 	GetComments(*Article, *[]Comment) error
-	GetComment(int, *Comment) error
+	GetAllComments(*Article) *gorm.DB
+	CountComments(*Article) (int, error)
+	GetComment(*Article, int, *Comment) error
+	UpdateComment(*Comment, string) error
+	GetCommentVersions(*Comment, *[]CommentVersion) error
+	CommentsAfter(*gorm.DB, interface{}) *gorm.DB
+	SortCommentsBy(*gorm.DB, interface{}) *gorm.DB
+	RefreshCommentsCounts() error
 }
 
 // NewComment initialize a new comment struct
 func NewComment(article *Article, user *User, body string) (*Comment, ValidationErrors) {
-	if body == "" {
-		return nil, ValidationErrors{
-			"body": []string{errorCommentBodyIsEmpty.Error()},
-		}
+	if errs := ValidateCommentBody(body); errs != nil {
+		return nil, errs
 	}
 
 	return &Comment{
@@ -43,31 +97,269 @@ func NewComment(article *Article, user *User, body string) (*Comment, Validation
 	}, nil
 }
 
+// ValidateCommentBody check the body of a new or edited comment
+// It returns nil when it is valid.
+func ValidateCommentBody(body string) ValidationErrors {
+	if body == "" {
+		return ValidationErrors{"body": []string{errorCommentBodyIsEmpty.Error()}}
+	}
+
+	if utf8.RuneCountInString(body) > MaxBodyLength {
+		return ValidationErrors{"body": []string{fmt.Sprintf(TOO_LONG_MSG, MaxBodyLength)}}
+	}
+
+	return nil
+}
+
+// ReplyTo make the comment a reply to parent
+// It returns the validation errors when parent can't be replied to.
+func (comment *Comment) ReplyTo(parent *Comment) ValidationErrors {
+	if parent.ArticleID != comment.Article.ID || parent.IsDeleted() {
+		return ValidationErrors{"parentId": []string{PARENT_NOT_FOUND_MSG}}
+	}
+
+	if parent.Depth+1 > MaxCommentDepth {
+		return ValidationErrors{"parentId": []string{fmt.Sprintf(MAX_DEPTH_MSG, MaxCommentDepth)}}
+	}
+
+	comment.ParentID = &parent.ID
+	comment.Depth = parent.Depth + 1
+
+	return nil
+}
+
+// IsDeleted check if the comment is in the trash, the deleted comments
+// are only listed as placeholders for their replies.
+func (comment *Comment) IsDeleted() bool {
+	return comment.DeletedAt != nil
+}
+
+// IsHidden check if the moderators hid the comment, the hidden comments
+// are only listed as placeholders for their replies.
+func (comment *Comment) IsHidden() bool {
+	return comment.HiddenAt != nil
+}
+
+// IsVisibleTo check if the given user can read the comment,
+// hidden comments are only visible to their author and the moderators.
+func (comment *Comment) IsVisibleTo(user *User) bool {
+	return !comment.IsHidden() || (user.ID != 0 && comment.UserID == user.ID) || user.IsModerator()
+}
+
 // CanBeDeletedBy check if the comment can be deleted by the given user
 func (comment *Comment) CanBeDeletedBy(user *User) bool {
 	return (user.Username == comment.User.Username)
 }
 
+// CanBeEditedBy check if the comment can be edited by the given user
+func (comment *Comment) CanBeEditedBy(user *User) bool {
+	return (user.Username == comment.User.Username)
+}
+
+// IsEditableAt check if the edit window of the comment is still open at now
+func (comment *Comment) IsEditableAt(now time.Time) bool {
+	return now.Before(comment.CreatedAt.Add(CommentEditWindow))
+}
+
+// IsEdited check if the comment body was edited after its creation
+func (comment *Comment) IsEdited() bool {
+	return comment.EditedAt != nil
+}
+
+// RenderedBody returns the body rendered to sanitized HTML, it is rendered on save
+// so only the comments saved before the rendering existed are rendered on the fly.
+func (comment *Comment) RenderedBody() string {
+	if comment.BodyHTML == "" && comment.Body != "" {
+		return markdown.Render(comment.Body)
+	}
+
+	return comment.BodyHTML
+}
+
+// Cursor returns the cursor pointing to this comment in a listing ordered by the given sort
+func (comment *Comment) Cursor(sort string) *Cursor {
+	return NewCursor(sort, comment.CreatedAt, comment.ID)
+}
+
 // CreateComment persist a new comment in the database
 func (db *DB) CreateComment(comment *Comment) (err error) {
-	err = db.Create(&comment).Error
+	tx := db.Begin()
+
+	// The article and the author are only referenced, saving them back would overwrite
+	// their counters and updated_at with the values loaded at the start of the request.
+	err = tx.Set("gorm:association_autoupdate", false).
+		Set("gorm:association_autocreate", false).
+		Create(&comment).Error
+
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	// The author of the article is notified of the new comment
+	var authorIDs []int
+	err = tx.Unscoped().Model(&Article{}).Where("id = ?", comment.ArticleID).Pluck("user_id", &authorIDs).Error
+
+	var notification *Notification
+	var notified bool
+	if err == nil && len(authorIDs) > 0 {
+		notification = &Notification{
+			UserID:    authorIDs[0],
+			ActorID:   comment.UserID,
+			Kind:      NotifyComment,
+			ArticleID: comment.ArticleID,
+			CommentID: comment.ID,
+		}
+		notified, err = notify(tx, notification)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+
+	if err = tx.Commit().Error; err == nil {
+		db.notified(notification, notified)
+	}
+
 	return
 }
 
-// DeleteComment delete a comment from the database
+// DeleteComment move a comment to the trash, it is purged by PurgeTrash
+// once the retention is over. A primary key must be provided.
 func (db *DB) DeleteComment(comment *Comment) (err error) {
+	if comment.ID == 0 {
+		return errorMissingPrimaryKey
+	}
+
 	err = db.Delete(&comment).Error
 	return
 }
 
 // GetComments get all comments for the givan article
 func (db *DB) GetComments(article *Article, comments *[]Comment) error {
-	err := db.Model(&article).Preload("User").Related(&comments).Error
+	err := db.GetAllComments(article).Find(comments).Error
 	return err
 }
 
-// GetComment get a comment for the given commentID
-func (db *DB) GetComment(commentID int, comment *Comment) error {
-	err := db.Preload("User").First(&comment, commentID).Error
+// GetAllComments return a scope query to fetch all comments for the given article.
+// The deleted comments with replies are kept so the replies are never orphaned,
+// see IsDeleted. You must call Find at the end to perform the query.
+func (db *DB) GetAllComments(article *Article) *gorm.DB {
+	return db.Unscoped().
+		Scopes(defaultCommentScope).
+		Where("comments.article_id = ?", article.ID).
+		Where(listedCommentsQuery)
+}
+
+// CountComments count the comments of the given article like its comments count does,
+// the placeholders listed by GetAllComments are not counted.
+func (db *DB) CountComments(article *Article) (int, error) {
+	var count int
+
+	err := db.Unscoped().
+		Model(&Comment{}).
+		Where("comments.article_id = ?", article.ID).
+		Where(countedCommentsQuery).
+		Count(&count).Error
+
+	return count, err
+}
+
+// GetComment get the comment of the given article with the given id
+func (db *DB) GetComment(article *Article, commentID int, comment *Comment) error {
+	err := db.Preload("User").
+		Where("comments.article_id = ?", article.ID).
+		First(comment, commentID).Error
 	return err
 }
+
+// RefreshCommentsCounts recount the comments of every article,
+// it fixes the counts the callbacks could not keep up to date (e.g. raw queries).
+func (db *DB) RefreshCommentsCounts() error {
+	return db.Exec(commentsCountQuery).Error
+}
+
+// CommentsAfter restrict the comments to the ones listed after the given cursor,
+// value argument can be *Cursor|string|url.Values
+// If a url.Values provided, it must contains a query string 'cursor' param name.
+func (DB) CommentsAfter(db *gorm.DB, value interface{}) *gorm.DB {
+	return keyset(db, "comments", commentSorts, value)
+}
+
+// SortCommentsBy replace the order of the comments by the sort found in value (default: oldest),
+// value argument can be string|url.Values
+// If a url.Values provided, it must contains a query string 'sort' param name.
+func (DB) SortCommentsBy(db *gorm.DB, value interface{}) *gorm.DB {
+	if order, ok := commentSorts[CommentsSort(value)]; ok {
+		return orderBy(db, "comments", order)
+	}
+
+	return db
+}
+
+// CommentsSort returns the name of the sort found in value (default: oldest)
+func CommentsSort(value interface{}) string {
+	return sortFrom(value, defaultCommentSort)
+}
+
+// ValidateCommentsSort check the 'sort' query string param of the comments listings
+// It returns nil when it is absent or valid.
+func ValidateCommentsSort(queryParams url.Values) ValidationErrors {
+	return validateSort(queryParams, commentSorts, defaultCommentSort)
+}
+
+// Callbacks
+
+// BeforeSave gorm callback
+// Body rendering
+func (comment *Comment) BeforeSave() (err error) {
+	comment.BodyHTML = markdown.Render(comment.Body)
+	return
+}
+
+// AfterCreate gorm callback
+// Increment the comments count of the article
+func (comment *Comment) AfterCreate(db *gorm.DB) error {
+	return incrementCommentsCount(db, comment.ArticleID, 1)
+}
+
+// AfterDelete gorm callback
+// Decrement the comments count of the article when the comment is moved to the trash,
+// the comments purged from the trash and the hidden ones were already uncounted.
+func (comment *Comment) AfterDelete(db *gorm.DB) error {
+	if comment.IsDeleted() || comment.IsHidden() {
+		return nil
+	}
+
+	return incrementCommentsCount(db, comment.ArticleID, -1)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Scopes															 		 //
+///////////////////////////////////////////////////////////////////////////////
+
+// Order comments by created_at ASC eager loading User
+// The id is used as a tie breaker to keep a stable order for cursors
+func defaultCommentScope(db *gorm.DB) *gorm.DB {
+	return db.Order("comments.created_at asc").
+		Order("comments.id asc").
+		Preload("User")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// incrementCommentsCount add delta to the comments count of the given article,
+// the articles in the trash are counted too so restoring them keeps their count.
+func incrementCommentsCount(db *gorm.DB, articleID int, delta int) error {
+	if articleID == 0 {
+		return nil
+	}
+
+	return db.Unscoped().
+		Model(&Article{}).
+		Where("id = ?", articleID).
+		UpdateColumn("comments_count", gorm.Expr("comments_count + ?", delta)).Error
+}
@@ -2,7 +2,9 @@ package models
 
 import (
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewComment(t *testing.T) {
@@ -26,6 +28,11 @@ func TestNewComment(t *testing.T) {
 			args{article: a, user: u, body: ""},
 			nil, true,
 		},
+		{
+			"get an error when body is too long",
+			args{article: a, user: u, body: strings.Repeat("a", MaxBodyLength+1)},
+			nil, true,
+		},
 		{
 			"initialize a new comment",
 			args{article: a, user: u, body: "A comment body"},
@@ -47,3 +54,36 @@ func TestNewComment(t *testing.T) {
 		})
 	}
 }
+
+func TestCommentReplyTo(t *testing.T) {
+	a := &Article{ID: 1}
+	deletedAt := time.Now()
+
+	tests := []struct {
+		name      string
+		parent    *Comment
+		wantDepth int
+		wantErr   bool
+	}{
+		{"reply to a top level comment", &Comment{ID: 1, ArticleID: 1}, 1, false},
+		{"reply to a reply", &Comment{ID: 2, ArticleID: 1, Depth: 1}, 2, false},
+		{"reply to a comment of another article", &Comment{ID: 3, ArticleID: 2}, 0, true},
+		{"reply to a deleted comment", &Comment{ID: 4, ArticleID: 1, DeletedAt: &deletedAt}, 0, true},
+		{"reply deeper than the max depth", &Comment{ID: 5, ArticleID: 1, Depth: MaxCommentDepth}, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comment := &Comment{Article: *a}
+			errs := comment.ReplyTo(tt.parent)
+
+			if (errs != nil) != tt.wantErr {
+				t.Errorf("ReplyTo() error = %v, wantErr %v", errs, tt.wantErr)
+				return
+			}
+			if comment.Depth != tt.wantDepth {
+				t.Errorf("ReplyTo() Depth = %v, want %v", comment.Depth, tt.wantDepth)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// CommentVersion is a previous body of an edited comment,
+// they are kept for the moderators.
+type CommentVersion struct {
+	ID        int
+	CommentID int    `gorm:"index:index_comment_versions_on_comment_id"`
+	Body      string `gorm:"type:text"`
+	// WrittenAt is when this body was written
+	WrittenAt time.Time
+	// CreatedAt is when this body was replaced
+	CreatedAt time.Time
+}
+
+// CommentEditWindow is how long after its creation a comment can be edited,
+// it can be changed at startup to fit the deployment.
+var CommentEditWindow = 15 * time.Minute
+
+// UpdateComment replace the body of a comment, the previous one is kept as a CommentVersion
+func (db *DB) UpdateComment(comment *Comment, body string) error {
+	if body == comment.Body {
+		return nil
+	}
+
+	writtenAt := comment.CreatedAt
+	if comment.IsEdited() {
+		writtenAt = *comment.EditedAt
+	}
+
+	tx := db.Begin()
+
+	version := CommentVersion{CommentID: comment.ID, Body: comment.Body, WrittenAt: writtenAt}
+	if err := tx.Create(&version).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	now := time.Now()
+	comment.Body = body
+	comment.EditedAt = &now
+
+	// Only the edited columns are written, the loaded comment may be stale
+	if err := tx.Select("body", "body_html", "edited_at", "updated_at").Save(comment).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// GetCommentVersions get the previous bodies of the given comment, last replaced first
+func (db *DB) GetCommentVersions(comment *Comment, versions *[]CommentVersion) error {
+	return db.Where("comment_id = ?", comment.ID).
+		Order("created_at desc").
+		Order("id desc").
+		Find(versions).Error
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Cursor is a position in a listing ordered by a sort column then by id.
+// It is handed to the clients as an opaque token, see String and ParseCursor.
+type Cursor struct {
+	// Sort is the name of the sort the listing is ordered by
+	Sort string
+	// Value is the value of the sort column, a time.Time or an int
+	Value interface{}
+	ID    int
+}
+
+// sortOrder is a way to order a listing, rows with the same
+// column value are ordered by id in the same direction.
+// keyset is false when the listing can't be paginated with cursors.
+type sortOrder struct {
+	column string
+	desc   bool
+	keyset bool
+}
+
+var (
+	errorInvalidCursor = errors.New("Invalid cursor")
+)
+
+const (
+	cursorSeparator = "|"
+	timeValue       = "t"
+	intValue        = "i"
+)
+
+// NewCursor returns a cursor pointing to the row with the given sort value and ID.
+func NewCursor(sort string, value interface{}, id int) *Cursor {
+	return &Cursor{Sort: sort, Value: value, ID: id}
+}
+
+// ParseCursor decode a token previously generated by Cursor.String
+func ParseCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errorInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), cursorSeparator)
+	if len(parts) != 4 {
+		return nil, errorInvalidCursor
+	}
+
+	var value interface{}
+
+	switch parts[1] {
+	case timeValue:
+		// The time is kept with its original offset, so it is bound exactly
+		// as it was stored when comparing time columns.
+		value, err = time.Parse(time.RFC3339Nano, parts[2])
+	case intValue:
+		value, err = strconv.Atoi(parts[2])
+	default:
+		err = errorInvalidCursor
+	}
+
+	if err != nil {
+		return nil, errorInvalidCursor
+	}
+
+	id, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return nil, errorInvalidCursor
+	}
+
+	return NewCursor(parts[0], value, id), nil
+}
+
+// String encode the cursor into an opaque url safe token
+func (c *Cursor) String() string {
+	var kind, value string
+
+	switch c.Value.(type) {
+	case time.Time:
+		kind, value = timeValue, c.Value.(time.Time).Format(time.RFC3339Nano)
+	case int:
+		kind, value = intValue, strconv.Itoa(c.Value.(int))
+	}
+
+	raw := strings.Join([]string{c.Sort, kind, value, strconv.Itoa(c.ID)}, cursorSeparator)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// cursorFrom extract a cursor from value, value argument can be *Cursor|string|url.Values
+// If a url.Values provided, it must contains a query string 'cursor' param name.
+// The second return value is false when no (valid) cursor is present.
+func cursorFrom(value interface{}) (*Cursor, bool) {
+	switch value.(type) {
+	case *Cursor:
+		cursor := value.(*Cursor)
+		return cursor, cursor != nil
+	case string:
+		cursor, err := ParseCursor(value.(string))
+		return cursor, err == nil
+	case url.Values:
+		return cursorFrom(value.(url.Values).Get("cursor"))
+	}
+
+	return nil, false
+}
+
+// keyset restrict the query to the rows of table located after the cursor
+// in a listing ordered with one of the given sorts.
+func keyset(db *gorm.DB, table string, sorts map[string]sortOrder, value interface{}) *gorm.DB {
+	cursor, ok := cursorFrom(value)
+	if !ok {
+		return db
+	}
+
+	order, ok := sorts[cursor.Sort]
+	if !ok || !order.keyset {
+		return db
+	}
+
+	operator := ">"
+	if order.desc {
+		operator = "<"
+	}
+
+	whereClause := fmt.Sprintf("%[1]v %[2]v ? OR (%[1]v = ? AND %[3]v.id %[2]v ?)", order.column, operator, table)
+
+	return db.Where(whereClause, cursor.Value, cursor.Value, cursor.ID)
+}
+
+// orderBy replace the order of the query by the given sort
+func orderBy(db *gorm.DB, table string, order sortOrder) *gorm.DB {
+	direction := "asc"
+	if order.desc {
+		direction = "desc"
+	}
+
+	return db.Order(order.column+" "+direction, true).
+		Order(table + ".id " + direction)
+}
+
+// sortFrom returns the sort name found in value or defaultSort when absent,
+// value argument can be string|url.Values
+// If a url.Values provided, it must contains a query string 'sort' param name.
+func sortFrom(value interface{}, defaultSort string) string {
+	var name string
+
+	switch value.(type) {
+	case string:
+		name = value.(string)
+	case url.Values:
+		name = value.(url.Values).Get("sort")
+	}
+
+	if name == "" {
+		return defaultSort
+	}
+
+	return name
+}
+
+// validateSort check the 'sort' query string param is one of sorts and that
+// the 'cursor' param, if any, was generated for the same sort.
+func validateSort(queryParams url.Values, sorts map[string]sortOrder, defaultSort string) ValidationErrors {
+	var errs = ValidationErrors{}
+	name := sortFrom(queryParams, defaultSort)
+
+	if order, present := sorts[name]; !present {
+		var names []string
+		for name := range sorts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		errs["sort"] = []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(names, ", "))}
+	} else if cursor, ok := cursorFrom(queryParams); ok && (cursor.Sort != name || !order.keyset) {
+		errs["cursor"] = []string{fmt.Sprintf(CURSOR_SORT_MSG, name)}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package models
+
+import "strings"
+
+// DiffLine is a line of a diff, Op is one of DiffEqual, DiffInsert or DiffDelete
+type DiffLine struct {
+	Op   string
+	Text string
+}
+
+// FieldDiff is the line by line diff of an article field between two revisions
+type FieldDiff struct {
+	Field string
+	Lines []DiffLine
+}
+
+const (
+	DiffEqual  = "="
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+// DiffRevisions returns the diff of the tracked fields changed between from and to
+func DiffRevisions(from *Revision, to *Revision) []FieldDiff {
+	diffs := []FieldDiff{}
+
+	for _, field := range revisionFields {
+		before, after := from.Field(field), to.Field(field)
+		if before == after {
+			continue
+		}
+
+		diffs = append(diffs, FieldDiff{
+			Field: field,
+			Lines: diffLines(strings.Split(before, "\n"), strings.Split(after, "\n")),
+		})
+	}
+
+	return diffs
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// diffLines compute the shortest edit from a to b using their longest common
+// subsequence. The common prefix and suffix are kept as is and the rest is
+// diffed in linear space, see hirschberg. Past maxDiffCells the lines left
+// are replaced as a whole, so the time spent on a diff stays bounded.
+func diffLines(a []string, b []string) []DiffLine {
+	var lines []DiffLine
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		lines = append(lines, DiffLine{DiffEqual, a[prefix]})
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	middleA, middleB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(middleA)*len(middleB) > maxDiffCells {
+		lines = appendLines(lines, DiffDelete, middleA)
+		lines = appendLines(lines, DiffInsert, middleB)
+	} else {
+		lines = hirschberg(lines, middleA, middleB)
+	}
+
+	return appendLines(lines, DiffEqual, a[len(a)-suffix:])
+}
+
+// maxDiffCells is the largest len(a)*len(b) diffed line by line by diffLines
+const maxDiffCells = 1 << 24
+
+// hirschberg append the shortest edit from a to b to lines. a is split in half
+// and b where the longest common subsequences of both halves add up the most,
+// so only two rows of lcs lengths are kept at a time.
+func hirschberg(lines []DiffLine, a []string, b []string) []DiffLine {
+	switch {
+	case len(a) == 0:
+		return appendLines(lines, DiffInsert, b)
+	case len(b) == 0:
+		return appendLines(lines, DiffDelete, a)
+	case len(a) == 1:
+		for j := range b {
+			if b[j] == a[0] {
+				lines = appendLines(lines, DiffInsert, b[:j])
+				lines = append(lines, DiffLine{DiffEqual, a[0]})
+				return appendLines(lines, DiffInsert, b[j+1:])
+			}
+		}
+		lines = append(lines, DiffLine{DiffDelete, a[0]})
+		return appendLines(lines, DiffInsert, b)
+	}
+
+	middle := len(a) / 2
+	heads := lcsPrefixLengths(a[:middle], b)
+	tails := lcsSuffixLengths(a[middle:], b)
+
+	split := 0
+	for j := range heads {
+		if heads[j]+tails[j] > heads[split]+tails[split] {
+			split = j
+		}
+	}
+
+	lines = hirschberg(lines, a[:middle], b[:split])
+	return hirschberg(lines, a[middle:], b[split:])
+}
+
+// lcsPrefixLengths returns the length of the longest common subsequence
+// of a and b[:j] for every j
+func lcsPrefixLengths(a []string, b []string) []int {
+	previous, current := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				current[j+1] = previous[j] + 1
+			} else if previous[j+1] >= current[j] {
+				current[j+1] = previous[j+1]
+			} else {
+				current[j+1] = current[j]
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous
+}
+
+// lcsSuffixLengths returns the length of the longest common subsequence
+// of a and b[j:] for every j
+func lcsSuffixLengths(a []string, b []string) []int {
+	previous, current := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				current[j] = previous[j+1] + 1
+			} else if previous[j] >= current[j+1] {
+				current[j] = previous[j]
+			} else {
+				current[j] = current[j+1]
+			}
+		}
+		previous, current = current, previous
+	}
+
+	return previous
+}
+
+// appendLines append texts to lines with the op
+func appendLines(lines []DiffLine, op string, texts []string) []DiffLine {
+	for _, text := range texts {
+		lines = append(lines, DiffLine{op, text})
+	}
+
+	return lines
+}
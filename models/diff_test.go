@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiffRevisions(t *testing.T) {
+	from := &Revision{Title: "title", Description: "desc", Body: "line 1\nline 2\nline 3"}
+
+	tests := []struct {
+		name string
+		to   *Revision
+		want []FieldDiff
+	}{
+		{
+			"nothing changed",
+			&Revision{Title: "title", Description: "desc", Body: "line 1\nline 2\nline 3"},
+			[]FieldDiff{},
+		},
+		{
+			"title replaced",
+			&Revision{Title: "new title", Description: "desc", Body: "line 1\nline 2\nline 3"},
+			[]FieldDiff{
+				{"title", []DiffLine{{DiffDelete, "title"}, {DiffInsert, "new title"}}},
+			},
+		},
+		{
+			"body lines moved",
+			&Revision{Title: "title", Description: "desc", Body: "line 3\nline 1\nline 2"},
+			[]FieldDiff{
+				{"body", []DiffLine{
+					{DiffInsert, "line 3"},
+					{DiffEqual, "line 1"},
+					{DiffEqual, "line 2"},
+					{DiffDelete, "line 3"},
+				}},
+			},
+		},
+		{
+			"body line changed and appended",
+			&Revision{Title: "title", Description: "desc", Body: "line 1\nline two\nline 3\nline 4"},
+			[]FieldDiff{
+				{"body", []DiffLine{
+					{DiffEqual, "line 1"},
+					{DiffDelete, "line 2"},
+					{DiffInsert, "line two"},
+					{DiffEqual, "line 3"},
+					{DiffInsert, "line 4"},
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffRevisions(from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffRevisions() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffRevisionsLongBodies(t *testing.T) {
+	tests := []struct {
+		name   string
+		lines  int
+		edit   func(i int, line string) string
+		equals int
+	}{
+		{"every other line changed", 3000, func(i int, line string) string {
+			if i%2 == 0 {
+				return line + " edited"
+			}
+			return line
+		}, 1500},
+		{"every line changed", 100000, func(i int, line string) string { return line + " edited" }, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, after := make([]string, tt.lines), make([]string, tt.lines)
+			for i := range before {
+				before[i] = fmt.Sprintf("line %d", i)
+				after[i] = tt.edit(i, before[i])
+			}
+
+			from := &Revision{Body: strings.Join(before, "\n")}
+			to := &Revision{Body: strings.Join(after, "\n")}
+
+			start := time.Now()
+			diffs := DiffRevisions(from, to)
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("DiffRevisions() took %v", elapsed)
+			}
+
+			counts := map[string]int{}
+			for _, line := range diffs[0].Lines {
+				counts[line.Op]++
+			}
+
+			want := map[string]int{DiffEqual: tt.equals, DiffDelete: tt.lines - tt.equals, DiffInsert: tt.lines - tt.equals}
+			for op := range want {
+				if counts[op] != want[op] {
+					t.Errorf("DiffRevisions() %v lines = %v, want %v", op, counts[op], want[op])
+				}
+			}
+		})
+	}
+}
@@ -1,23 +1,63 @@
 package models
 
-import "github.com/jinzhu/gorm"
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
 
 type Favorite struct {
 	ID        int
 	User      User
-	UserID    int `gorm:"index:index_favorites_on_user_id"`
+	UserID    int `gorm:"index:index_favorites_on_user_id;unique_index:index_favorites_on_user_id_and_article_id"`
 	Article   Article
-	ArticleID int `gorm:"index:index_favorites_on_article_id"`
+	ArticleID int `gorm:"index:index_favorites_on_article_id;unique_index:index_favorites_on_user_id_and_article_id"`
+	CreatedAt time.Time
 }
 
+// insertFavoriteQuery add a favorite unless the user already favorited the article,
+// the check and the insert are a single statement so concurrent requests can't both insert.
+const insertFavoriteQuery = `INSERT INTO favorites (user_id, article_id, created_at)
+	SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM favorites WHERE user_id = ? AND article_id = ?)`
+
+// favoritesCountQuery set the favorites count of the articles to their number of favorites
+const favoritesCountQuery = `UPDATE articles SET favorites_count = (SELECT COUNT(*) FROM favorites
+	WHERE favorites.article_id = articles.id)`
+
+// favoritesUniqueIndex is the unique index of the favorites on user_id and article_id
+const favoritesUniqueIndex = "index_favorites_on_user_id_and_article_id"
+
+// duplicateFavoritesQuery delete the favorites added more than once by the same user,
+// they were possible before the unique index.
+const duplicateFavoritesQuery = `DELETE FROM favorites WHERE id NOT IN
+	(SELECT MIN(id) FROM favorites GROUP BY user_id, article_id)`
+
 func (f *Favorite) AfterCreate(db *gorm.DB) (err error) {
-	var a = &Article{ID: f.ArticleID}
-	err = db.First(&a).Update("favorites_count", gorm.Expr("favorites_count + ?", 1)).Error
-	return
+	return incrementFavoritesCount(db, f.ArticleID, 1)
 }
 
 func (f *Favorite) AfterDelete(db *gorm.DB) (err error) {
-	var a = &Article{ID: f.ArticleID}
-	err = db.First(&a).Update("favorites_count", gorm.Expr("favorites_count - ?", 1)).Error
-	return
+	return incrementFavoritesCount(db, f.ArticleID, -1)
+}
+
+// RefreshFavoritesCounts recount the favorites of every article from the favorites table
+func (db *DB) RefreshFavoritesCounts() error {
+	return db.Exec(favoritesCountQuery).Error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// incrementFavoritesCount add delta to the favorites count of the given article in a single statement,
+// so concurrent updates can't overwrite each other.
+func incrementFavoritesCount(db *gorm.DB, articleID int, delta int64) error {
+	if articleID == 0 || delta == 0 {
+		return nil
+	}
+
+	return db.Unscoped().
+		Model(&Article{}).
+		Where("id = ?", articleID).
+		UpdateColumn("favorites_count", gorm.Expr("favorites_count + ?", delta)).Error
 }
@@ -0,0 +1,276 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type FilterStorer interface {
+	FilterContent(*Content) (ValidationErrors, ValidationErrors)
+	FlagContent(*Article, *Comment, ValidationErrors) error
+}
+
+// Content is the text of an article or a comment checked by the content filters
+type Content struct {
+	// ID is the article or comment being updated, 0 when it is being created
+	ID     int
+	Kind   string
+	UserID int
+	// Fields are the texts of the content keyed by field name
+	Fields map[string]string
+}
+
+// Kinds of content
+const (
+	ContentArticle = "article"
+	ContentComment = "comment"
+)
+
+// Actions taken on the content caught by a filter
+const (
+	// FilterReject refuse the content with the filter validation errors
+	FilterReject = "reject"
+	// FilterFlag accept the content and report it to the moderators
+	FilterFlag = "flag"
+)
+
+// FilterReason is the reason of the reports made by the content filters
+const FilterReason = "filter"
+
+// filterActions are the accepted actions of a FilterRule
+var filterActions = []string{FilterReject, FilterFlag}
+
+// ContentFilter check the content written by the users before it is saved
+type ContentFilter interface {
+	// Check returns the problems found in the content keyed by field, nil when there is none
+	Check(db *gorm.DB, content *Content) ValidationErrors
+}
+
+// FilterRule is a content filter along with the action taken on the content it catches
+type FilterRule struct {
+	Filter ContentFilter
+	Action string
+}
+
+// ContentFilters is the pipeline run on the articles and comments created or updated,
+// in order. It is empty by default and set up at startup to fit the deployment.
+var ContentFilters []FilterRule
+
+// NewFilterRule initialize a rule, the action must be FilterReject or FilterFlag
+func NewFilterRule(filter ContentFilter, action string) (FilterRule, error) {
+	if !contains(filterActions, action) {
+		return FilterRule{}, fmt.Errorf(NOT_IN_LIST_MSG, strings.Join(filterActions, ", "))
+	}
+
+	return FilterRule{Filter: filter, Action: action}, nil
+}
+
+// Content returns the fields of the article checked by the content filters
+func (a *Article) Content() *Content {
+	// A new article only has its user until it is saved
+	userID := a.UserID
+	if userID == 0 {
+		userID = a.User.ID
+	}
+
+	return &Content{
+		ID:     a.ID,
+		Kind:   ContentArticle,
+		UserID: userID,
+		Fields: map[string]string{"title": a.Title, "description": a.Description, "body": a.Body},
+	}
+}
+
+// Content returns the fields of the comment checked by the content filters
+func (c *Comment) Content() *Content {
+	userID := c.UserID
+	if userID == 0 {
+		userID = c.User.ID
+	}
+
+	return &Content{
+		ID:     c.ID,
+		Kind:   ContentComment,
+		UserID: userID,
+		Fields: map[string]string{"body": c.Body},
+	}
+}
+
+// WordFilter catch the content containing one of its words, case insensitive.
+// It is built with NewWordFilter so the words are compiled once.
+type WordFilter struct {
+	// pattern match one of the words, nil when there is none
+	pattern *regexp.Regexp
+}
+
+// NewWordFilter initialize a filter catching the given words, the blank ones are dropped
+func NewWordFilter(words []string) WordFilter {
+	var quoted []string
+	for _, word := range words {
+		if word = strings.TrimSpace(word); word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	if len(quoted) == 0 {
+		return WordFilter{}
+	}
+
+	// \b only knows the ASCII letters, the words are delimited by any rune but a letter, a digit or a mark
+	return WordFilter{pattern: regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}\p{M}])(?:` + strings.Join(quoted, "|") + `)(?:$|[^\p{L}\p{N}\p{M}])`)}
+}
+
+func (f WordFilter) Check(db *gorm.DB, content *Content) ValidationErrors {
+	if f.pattern == nil {
+		return nil
+	}
+
+	return checkFields(content, func(text string) string {
+		if f.pattern.MatchString(text) {
+			return BANNED_WORDS_MSG
+		}
+		return ""
+	})
+}
+
+// linkPattern match the URLs of a text, starting with a scheme or with www.
+var linkPattern = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+
+// LinkFilter catch the content with more than MaxLinks links in one of its fields
+type LinkFilter struct {
+	MaxLinks int
+}
+
+func (f LinkFilter) Check(db *gorm.DB, content *Content) ValidationErrors {
+	return checkFields(content, func(text string) string {
+		if len(linkPattern.FindAllString(text, -1)) > f.MaxLinks {
+			return fmt.Sprintf(TOO_MANY_LINKS_MSG, f.MaxLinks)
+		}
+		return ""
+	})
+}
+
+// DuplicateFilter catch the content whose body is the same as one of the articles
+// or comments, depending on its kind, posted by its author during the last Window.
+type DuplicateFilter struct {
+	Window time.Duration
+}
+
+func (f DuplicateFilter) Check(db *gorm.DB, content *Content) ValidationErrors {
+	body := strings.TrimSpace(content.Fields["body"])
+	if body == "" {
+		return nil
+	}
+
+	table := "articles"
+	if content.Kind == ContentComment {
+		table = "comments"
+	}
+
+	var count int
+	db.Table(table).
+		Where("user_id = ? AND id != ? AND created_at > ?", content.UserID, content.ID, time.Now().Add(-f.Window)).
+		Where("TRIM(body) = ?", body).
+		Count(&count)
+
+	if count > 0 {
+		return ValidationErrors{"body": []string{DUPLICATE_CONTENT_MSG}}
+	}
+
+	return nil
+}
+
+// FilterContent run the content through the ContentFilters
+// It returns the errors of the filters rejecting the content and the ones flagging it,
+// each is nil when no filter did.
+func (db *DB) FilterContent(content *Content) (ValidationErrors, ValidationErrors) {
+	var rejected, flagged ValidationErrors
+
+	for _, rule := range ContentFilters {
+		errs := rule.Filter.Check(db.DB, content)
+		if errs == nil {
+			continue
+		}
+
+		if rule.Action == FilterFlag {
+			flagged = mergeErrors(flagged, errs)
+		} else {
+			rejected = mergeErrors(rejected, errs)
+		}
+	}
+
+	return rejected, flagged
+}
+
+// FlagContent report the article, or the comment when it is not nil, to the moderators
+// on behalf of the content filters. The errors are the reason the filters flagged it.
+// A content flagged again is put back in the moderation queue.
+func (db *DB) FlagContent(article *Article, comment *Comment, errs ValidationErrors) error {
+	report := Report{ArticleID: article.ID, AuthorID: article.UserID}
+	if comment != nil {
+		report.CommentID = comment.ID
+		report.AuthorID = comment.UserID
+	}
+
+	// The filters report with no reporter so their reports are told apart from the users' ones
+	return db.Where("reporter_id = 0 AND article_id = ? AND comment_id = ?", report.ArticleID, report.CommentID).
+		Assign(map[string]interface{}{
+			"author_id":    report.AuthorID,
+			"reason":       FilterReason,
+			"details":      describeErrors(errs),
+			"status":       ReportOpen,
+			"action":       "",
+			"moderator_id": 0,
+			"claimed_at":   nil,
+			"resolved_at":  nil,
+		}).
+		FirstOrCreate(&report).Error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// checkFields run the check on every field of the content,
+// the check returns the error message of the field or "" when it passes.
+func checkFields(content *Content, check func(string) string) ValidationErrors {
+	var errs ValidationErrors
+
+	for field, text := range content.Fields {
+		if msg := check(text); msg != "" {
+			errs = mergeErrors(errs, ValidationErrors{field: []string{msg}})
+		}
+	}
+
+	return errs
+}
+
+func mergeErrors(errs ValidationErrors, others ValidationErrors) ValidationErrors {
+	if errs == nil {
+		errs = ValidationErrors{}
+	}
+
+	for field, messages := range others {
+		errs[field] = append(errs[field], messages...)
+	}
+
+	return errs
+}
+
+// describeErrors format the errors as "field: message" lines sorted by field
+func describeErrors(errs ValidationErrors) string {
+	var lines []string
+	for field, messages := range errs {
+		for _, msg := range messages {
+			lines = append(lines, field+": "+msg)
+		}
+	}
+	sort.Strings(lines)
+
+	return strings.Join(lines, "\n")
+}
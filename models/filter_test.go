@@ -0,0 +1,84 @@
+package models
+
+import "testing"
+
+func TestWordFilter(t *testing.T) {
+	filter := NewWordFilter([]string{"casino", " cheap pills", "", "café", "казино"})
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   []string
+	}{
+		{"clean content", map[string]string{"body": "A post about casinos history"}, nil},
+		{"banned word", map[string]string{"body": "Visit my Casino tonight"}, []string{"body"}},
+		{"banned words in several fields", map[string]string{"title": "CASINO", "body": "Buy cheap pills"}, []string{"title", "body"}},
+		{"empty content", map[string]string{"body": ""}, nil},
+		{"non ASCII banned word", map[string]string{"body": "Un café, noir"}, []string{"body"}},
+		{"non ASCII word containing a banned word", map[string]string{"body": "Les cafés du coin"}, nil},
+		{"banned word in another script", map[string]string{"body": "Играть в КАЗИНО"}, []string{"body"}},
+		{"word in another script containing a banned word", map[string]string{"body": "казинопорт"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := filter.Check(nil, &Content{Fields: tt.fields})
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Check() = %v, want errors on %v", errs, tt.want)
+			}
+			for _, field := range tt.want {
+				if len(errs[field]) != 1 || errs[field][0] != BANNED_WORDS_MSG {
+					t.Errorf("Check()[%v] = %v, want %v", field, errs[field], BANNED_WORDS_MSG)
+				}
+			}
+		})
+	}
+}
+
+func TestWordFilterWithoutWords(t *testing.T) {
+	if errs := NewWordFilter([]string{" ", ""}).Check(nil, &Content{Fields: map[string]string{"body": "Anything"}}); errs != nil {
+		t.Errorf("Check() = %v, want nil", errs)
+	}
+}
+
+func TestLinkFilter(t *testing.T) {
+	filter := LinkFilter{MaxLinks: 2}
+
+	tests := []struct {
+		name    string
+		body    string
+		wantErr bool
+	}{
+		{"no link", "Just some text", false},
+		{"as many links as allowed", "See https://example.com and [this](http://example.org/page)", false},
+		{"too many links", "https://a.example HTTP://b.example www.c.example", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := filter.Check(nil, &Content{Fields: map[string]string{"body": tt.body}})
+			if (errs != nil) != tt.wantErr {
+				t.Errorf("Check() error = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewFilterRule(t *testing.T) {
+	tests := []struct {
+		action  string
+		wantErr bool
+	}{
+		{FilterReject, false},
+		{FilterFlag, false},
+		{"delete", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.action, func(t *testing.T) {
+			if _, err := NewFilterRule(LinkFilter{}, tt.action); (err != nil) != tt.wantErr {
+				t.Errorf("NewFilterRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
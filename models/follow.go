@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type FollowStorer interface {
+	FollowUser(*User, *User) error
+	UnfollowUser(*User, *User) error
+	IsFollowing(int, int) bool
+	FilterFollowedBy(*gorm.DB, *User) *gorm.DB
+}
+
+// Follow is a user following the articles of another one
+type Follow struct {
+	ID         int
+	FollowerID int `gorm:"unique_index:index_follows_on_follower_id_and_followed_id"`
+	FollowedID int `gorm:"unique_index:index_follows_on_follower_id_and_followed_id;index:index_follows_on_followed_id"`
+	CreatedAt  time.Time
+}
+
+// insertFollowQuery add a follow unless the follower already follows the user,
+// the check and the insert are a single statement so concurrent requests can't both insert.
+const insertFollowQuery = `INSERT INTO follows (follower_id, followed_id, created_at)
+	SELECT ?, ?, ? WHERE NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followed_id = ?)`
+
+// FollowUser make the follower follow the followed user, who is notified
+// It is idempotent: nothing changes when the follower already follows them.
+func (db *DB) FollowUser(follower *User, followed *User) error {
+	tx := db.Begin()
+
+	query := tx.Exec(insertFollowQuery, follower.ID, followed.ID, time.Now(), follower.ID, followed.ID)
+
+	notification, notified := &Notification{UserID: followed.ID, ActorID: follower.ID, Kind: NotifyFollow}, false
+
+	err := query.Error
+	if err == nil && query.RowsAffected > 0 {
+		notified, err = notify(tx, notification)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	db.notified(notification, notified)
+	return nil
+}
+
+// UnfollowUser make the follower stop following the followed user
+// It is idempotent: nothing changes when the follower doesn't follow them.
+func (db *DB) UnfollowUser(follower *User, followed *User) error {
+	return db.Where("follower_id = ? AND followed_id = ?", follower.ID, followed.ID).Delete(Follow{}).Error
+}
+
+// IsFollowing check if the given userIDFrom follows userIDTo
+func (db *DB) IsFollowing(userIDFrom int, userIDTo int) bool {
+	var count int
+	db.Model(&Follow{}).
+		Where("follower_id = ? AND followed_id = ?", userIDFrom, userIDTo).
+		Count(&count)
+
+	return count > 0
+}
+
+// FilterFollowedBy filter the articles authored by the users the given user follows
+func (DB) FilterFollowedBy(db *gorm.DB, follower *User) *gorm.DB {
+	return db.Where("articles.user_id IN (SELECT follows.followed_id FROM follows WHERE follows.follower_id = ?)", follower.ID)
+}
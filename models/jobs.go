@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// RunEvery run job right away then every interval until stop is closed.
+// Errors returned by job are passed to onError.
+func RunEvery(interval time.Duration, stop <-chan struct{}, job func() error, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			onError(err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
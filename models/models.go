@@ -9,11 +9,23 @@ type Datastorer interface {
 	ArticleStorer
 	CommentStorer
 	TagStorer
+	SearchStorer
+	TrendingStorer
+	RevisionStorer
+	TrashStorer
+	ReportStorer
+	FilterStorer
+	FollowStorer
+	BlockStorer
+	NotificationStorer
 	InitSchema()
+	Reconcile() error
 }
 
 type DB struct {
 	*gorm.DB
+	// Notified is called with each notification once it is saved, it can be nil
+	Notified func(*Notification)
 }
 
 func NewDB(dialect, dbName string) (*DB, error) {
@@ -21,21 +33,91 @@ func NewDB(dialect, dbName string) (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DB{db}, nil
+	return &DB{DB: db}, nil
 }
 
+// InitSchema migrate the tables and their indexes, it only writes to the existing rows
+// the first time an index is added over them. See Reconcile for the backfills.
 func (db *DB) InitSchema() {
+	// The duplicated favorites would keep the unique index from being created
+	if db.HasTable(&Favorite{}) && !db.Dialect().HasIndex("favorites", favoritesUniqueIndex) {
+		db.Exec(duplicateFavoritesQuery)
+	}
 	db.AutoMigrate(&Favorite{})
 	db.AutoMigrate(&User{})
 	db.AutoMigrate(&Article{})
 	db.AutoMigrate(&Tag{})
 	db.AutoMigrate(&Comment{})
+	db.AutoMigrate(&CommentVersion{})
+	db.AutoMigrate(&Revision{})
+	db.AutoMigrate(&Report{})
+	db.AutoMigrate(&Follow{})
+	db.AutoMigrate(&Block{})
+	db.AutoMigrate(&Notification{})
+	db.AutoMigrate(&NotificationPreference{})
 	db.Table("taggings").AddUniqueIndex("taggings_idx", "article_id", "user_id")
+	setupSearchIndex(db.DB)
+}
+
+// Reconcile recount the counters of every tag and article, backfill the columns of the
+// articles saved before they existed and rebuild the search index. It goes through whole
+// tables so it is only run on demand, e.g. after an upgrade or raw queries, never on boot.
+func (db *DB) Reconcile() error {
+	for _, refresh := range []func() error{
+		db.RefreshTaggingsCounts,
+		db.RefreshCommentsCounts,
+		db.RefreshFavoritesCounts,
+	} {
+		if err := refresh(); err != nil {
+			return err
+		}
+	}
+
+	if err := backfillSummaries(db.DB); err != nil {
+		return err
+	}
+
+	// Articles created before statuses existed are published since their creation
+	err := db.Model(&Article{}).
+		Where("status = ? AND published_at IS NULL", StatusPublished).
+		UpdateColumn("published_at", gorm.Expr("created_at")).Error
+
+	if err != nil {
+		return err
+	}
+
+	// The index is only kept up to date by the callbacks, the articles saved
+	// before it existed or with raw queries are not in it.
+	return db.ReindexArticles()
 }
 
 type ValidationErrors map[string][]string
 
 const (
-	EMPTY_MSG string = "Value can't be empty"
-	TAKEN_MSG string = "Value entered is taken"
+	EMPTY_MSG        string = "Value can't be empty"
+	TAKEN_MSG        string = "Value entered is taken"
+	NOT_IN_RANGE_MSG string = "Value must be an integer between %d and %d"
+	NEGATIVE_MSG     string = "Value must be an integer greater than or equal to 0"
+	INVALID_DATE_MSG string = "Value must be a date formatted as YYYY-MM-DD or RFC3339"
+	NOT_IN_LIST_MSG  string = "Value must be one of: %v"
+	CURSOR_SORT_MSG  string = "Value is not a cursor for the %v sort"
+	TOO_LONG_MSG     string = "Value can't be longer than %d characters"
+	NOT_STRING_MSG   string = "Value must be a string"
+	RESERVED_MSG     string = "Value can't be used, its slug %v is reserved"
+
+	PARENT_NOT_FOUND_MSG string = "Value is not a comment of this article"
+	MAX_DEPTH_MSG        string = "Replies can't be nested more than %d levels deep"
+	EDIT_WINDOW_MSG      string = "Comments can only be edited during %v after being posted"
+
+	ALREADY_REPORTED_MSG string = "You already reported this content"
+	SUSPENDED_MSG        string = "Your account is suspended"
+	BLOCKED_MSG          string = "You can't interact with this user"
+	SELF_MSG             string = "You can't %v yourself"
+
+	BANNED_WORDS_MSG      string = "Value contains words that are not allowed"
+	TOO_MANY_LINKS_MSG    string = "Value can't contain more than %d links"
+	DUPLICATE_CONTENT_MSG string = "Value is the same as one of your recent posts"
+
+	IDEMPOTENCY_REUSED_MSG      string = "Value was already used for another request"
+	IDEMPOTENCY_IN_PROGRESS_MSG string = "A request with this Idempotency-Key is already in progress"
 )
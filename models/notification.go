@@ -0,0 +1,235 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type NotificationStorer interface {
+	GetNotifications(*User, url.Values) ([]Notification, error)
+	CountNotifications(*User) (int, int, error)
+	GetNotification(*User, int, *Notification) error
+	MarkNotificationRead(*Notification, bool) error
+	MarkAllNotificationsRead(*User) error
+	GetNotificationPreferences(*User) (map[string]bool, error)
+	UpdateNotificationPreferences(*User, map[string]bool) error
+}
+
+// Notification tells a user, the recipient, what another user, the actor, did
+// with their content or their profile.
+type Notification struct {
+	ID     int
+	User   User
+	UserID int `gorm:"index:index_notifications_on_user_id"`
+	Actor  User
+	// ActorID is the user who commented, favorited or followed
+	ActorID int
+	Kind    string
+	// ArticleID is the commented or favorited article, 0 for a follow
+	Article   Article
+	ArticleID int
+	// CommentID is the new comment, 0 unless the kind is NotifyComment
+	CommentID int
+	// ReadAt is when the user read the notification, nil while it is unread
+	ReadAt    *time.Time
+	CreatedAt time.Time
+}
+
+// NotificationPreference turns the notifications of a kind on or off for a user,
+// the kinds without preference are on.
+type NotificationPreference struct {
+	ID      int
+	UserID  int    `gorm:"unique_index:index_notification_preferences_on_user_id_and_kind"`
+	Kind    string `gorm:"unique_index:index_notification_preferences_on_user_id_and_kind"`
+	Enabled bool
+}
+
+// Kinds of notifications
+const (
+	NotifyComment  = "comment"
+	NotifyFavorite = "favorite"
+	NotifyFollow   = "follow"
+)
+
+// notificationKinds are the accepted kinds of the notification preferences
+var notificationKinds = []string{NotifyComment, NotifyFavorite, NotifyFollow}
+
+// IsRead check if the user read the notification
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// GetNotifications get the notifications of the user, newest first. Only the unread ones
+// are returned when the 'unread' query string param is true. They are paginated
+// with the limit and offset params.
+func (db *DB) GetNotifications(u *User, queryParams url.Values) ([]Notification, error) {
+	var notifications []Notification
+
+	query := db.Preload("Actor").
+		Preload("Article").
+		Where("notifications.user_id = ?", u.ID)
+
+	if unread, _ := strconv.ParseBool(queryParams.Get("unread")); unread {
+		query = query.Where("notifications.read_at IS NULL")
+	}
+
+	err := query.Order("notifications.created_at desc").
+		Order("notifications.id desc").
+		Limit(PageSize(queryParams)).
+		Offset(PageOffset(queryParams)).
+		Find(&notifications).Error
+
+	return notifications, err
+}
+
+// CountNotifications count the notifications of the user, it returns the total and the unread ones
+func (db *DB) CountNotifications(u *User) (int, int, error) {
+	var total, unread int
+
+	err := db.Model(&Notification{}).Where("user_id = ?", u.ID).Count(&total).Error
+	if err != nil {
+		return 0, 0, err
+	}
+
+	err = db.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", u.ID).Count(&unread).Error
+	return total, unread, err
+}
+
+// GetNotification get the notification of the user with the given id
+func (db *DB) GetNotification(u *User, notificationID int, notification *Notification) error {
+	return db.Preload("Actor").
+		Preload("Article").
+		Where("notifications.user_id = ?", u.ID).
+		First(notification, notificationID).Error
+}
+
+// MarkNotificationRead mark the notification as read, or unread when read is false
+func (db *DB) MarkNotificationRead(notification *Notification, read bool) error {
+	var readAt *time.Time
+	if read {
+		now := time.Now()
+		readAt = &now
+	}
+
+	err := db.Model(&Notification{}).
+		Where("id = ?", notification.ID).
+		UpdateColumn("read_at", readAt).Error
+
+	if err == nil {
+		notification.ReadAt = readAt
+	}
+
+	return err
+}
+
+// MarkAllNotificationsRead mark every unread notification of the user as read
+func (db *DB) MarkAllNotificationsRead(u *User) error {
+	return db.Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", u.ID).
+		UpdateColumn("read_at", time.Now()).Error
+}
+
+// GetNotificationPreferences get whether each kind of notifications is on for the user
+func (db *DB) GetNotificationPreferences(u *User) (map[string]bool, error) {
+	var preferences []NotificationPreference
+	if err := db.Where("user_id = ?", u.ID).Find(&preferences).Error; err != nil {
+		return nil, err
+	}
+
+	enabled := map[string]bool{}
+	for _, kind := range notificationKinds {
+		enabled[kind] = true
+	}
+
+	for _, preference := range preferences {
+		enabled[preference.Kind] = preference.Enabled
+	}
+
+	return enabled, nil
+}
+
+// UpdateNotificationPreferences turn the given kinds of notifications on or off for the user,
+// the other kinds are left as they are.
+func (db *DB) UpdateNotificationPreferences(u *User, enabled map[string]bool) error {
+	tx := db.Begin()
+
+	for kind, on := range enabled {
+		preference := NotificationPreference{UserID: u.ID, Kind: kind}
+
+		err := tx.Where(preference).
+			Assign(map[string]interface{}{"enabled": on}).
+			FirstOrCreate(&preference).Error
+
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// ValidateNotifications check the query string params of the notifications list
+// It returns nil when they are valid.
+func ValidateNotifications(queryParams url.Values) ValidationErrors {
+	if unread := queryParams.Get("unread"); unread != "" {
+		if _, err := strconv.ParseBool(unread); err != nil {
+			return ValidationErrors{"unread": []string{fmt.Sprintf(NOT_IN_LIST_MSG, "true, false")}}
+		}
+	}
+
+	return nil
+}
+
+// ValidateNotificationPreferences check the kinds of the preferences
+// It returns nil when they are valid.
+func ValidateNotificationPreferences(enabled map[string]bool) ValidationErrors {
+	for kind := range enabled {
+		if !contains(notificationKinds, kind) {
+			return ValidationErrors{kind: []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(notificationKinds, ", "))}}
+		}
+	}
+
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// notified pass the saved notification to the Notified listener, if any
+func (db *DB) notified(notification *Notification, created bool) {
+	if created && db.Notified != nil {
+		db.Notified(notification)
+	}
+}
+
+// notify create the notification unless its recipient is the actor, turned its kind off,
+// or blocks or mutes the actor. It returns false when the notification is not created.
+func notify(db *gorm.DB, notification *Notification) (bool, error) {
+	if notification.UserID == 0 || notification.UserID == notification.ActorID {
+		return false, nil
+	}
+
+	var count int
+	err := db.Model(&NotificationPreference{}).
+		Where("user_id = ? AND kind = ? AND enabled = ?", notification.UserID, notification.Kind, false).
+		Count(&count).Error
+
+	if err == nil && count == 0 {
+		err = db.Model(&Block{}).
+			Where("user_id = ? AND target_id = ?", notification.UserID, notification.ActorID).
+			Count(&count).Error
+	}
+
+	if err != nil || count > 0 {
+		return false, err
+	}
+
+	return true, db.Create(notification).Error
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestValidateNotifications(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   url.Values
+		wantErr bool
+	}{
+		{"no param", url.Values{}, false},
+		{"unread only", url.Values{"unread": {"true"}}, false},
+		{"every notification", url.Values{"unread": {"false"}}, false},
+		{"not a boolean", url.Values{"unread": {"maybe"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := ValidateNotifications(tt.query); (errs != nil) != tt.wantErr {
+				t.Errorf("ValidateNotifications() error = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNotificationPreferences(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences map[string]bool
+		wantErr     bool
+	}{
+		{"no preference", map[string]bool{}, false},
+		{"known kinds", map[string]bool{NotifyComment: false, NotifyFollow: true}, false},
+		{"unknown kind", map[string]bool{NotifyFavorite: true, "mention": false}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if errs := ValidateNotificationPreferences(tt.preferences); (errs != nil) != tt.wantErr {
+				t.Errorf("ValidateNotificationPreferences() error = %v, wantErr %v", errs, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type ReportStorer interface {
+	CreateReport(*Report) error
+	HasReported(*User, *Article, *Comment) bool
+	GetReport(int, *Report) error
+	GetReports(url.Values) ([]Report, error)
+	ClaimReport(*Report, *User) error
+	ResolveReport(*Report, *User, string) error
+}
+
+// Report is a user flagging an article, or one of its comments, to the moderators
+type Report struct {
+	ID         int
+	Reporter   User
+	ReporterID int `gorm:"unique_index:index_reports_on_reporter_and_target"`
+	Article    Article
+	ArticleID  int `gorm:"unique_index:index_reports_on_reporter_and_target;index:index_reports_on_target"`
+	// CommentID is the reported comment, 0 when the article itself is reported
+	Comment   Comment
+	CommentID int `gorm:"unique_index:index_reports_on_reporter_and_target;index:index_reports_on_target"`
+	// AuthorID is the author of the reported content, the one suspended by ActionSuspend
+	AuthorID    int
+	Reason      string
+	Details     string `gorm:"type:text"`
+	Status      string `gorm:"index:index_reports_on_status;default:'open'"`
+	Moderator   User
+	ModeratorID int
+	// Action is how the moderator resolved the report
+	Action     string
+	ClaimedAt  *time.Time
+	ResolvedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Report statuses, an open report is claimed by a moderator before being resolved
+const (
+	ReportOpen     = "open"
+	ReportClaimed  = "claimed"
+	ReportResolved = "resolved"
+)
+
+// Moderation actions resolving a report, they apply to every report of the content
+const (
+	ActionDismiss = "dismiss"
+	ActionHide    = "hide"
+	ActionSuspend = "suspend"
+)
+
+// reportReasons are the accepted reasons of a report
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "other"}
+
+// reportStatuses are the accepted values of the 'status' query string param of the queue
+var reportStatuses = []string{ReportOpen, ReportClaimed, ReportResolved}
+
+// moderationActions are the accepted moderation actions
+var moderationActions = []string{ActionDismiss, ActionHide, ActionSuspend}
+
+// ReportsToHide is how many distinct users must report a content to hide it
+// until a moderator reviews it, 0 disables it. It can be changed at startup to fit the deployment.
+var ReportsToHide = 3
+
+var (
+	errorReportNotClaimable  = errors.New("This report is claimed by another moderator")
+	errorReportNotResolvable = errors.New("This report is already resolved")
+)
+
+// NewReport initialize a new report of the article, or of the comment when it is not nil
+func NewReport(reporter *User, article *Article, comment *Comment, reason string, details string) (*Report, ValidationErrors) {
+	if !contains(reportReasons, reason) {
+		return nil, ValidationErrors{"reason": []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(reportReasons, ", "))}}
+	}
+
+	report := &Report{
+		ReporterID: reporter.ID,
+		ArticleID:  article.ID,
+		AuthorID:   article.UserID,
+		Reason:     reason,
+		Details:    details,
+		Status:     ReportOpen,
+	}
+
+	if comment != nil {
+		report.CommentID = comment.ID
+		report.AuthorID = comment.UserID
+	}
+
+	return report, nil
+}
+
+// IsComment check if the report is about a comment rather than an article
+func (r *Report) IsComment() bool {
+	return r.CommentID != 0
+}
+
+// CanBeClaimedBy check if the given moderator can claim the report
+func (r *Report) CanBeClaimedBy(moderator *User) bool {
+	return r.Status == ReportOpen || (r.Status == ReportClaimed && r.ModeratorID == moderator.ID)
+}
+
+// CanBeResolvedBy check if the given moderator can resolve the report,
+// the reports claimed by another moderator are left to them.
+func (r *Report) CanBeResolvedBy(moderator *User) bool {
+	return r.CanBeClaimedBy(moderator)
+}
+
+// CreateReport persist a new report, the content is hidden once enough users reported it
+func (db *DB) CreateReport(report *Report) error {
+	tx := db.Begin()
+
+	if err := tx.Create(report).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The reports of the content filters have no reporter, they are not users
+	var reporters int
+	err := tx.Model(&Report{}).
+		Where("article_id = ? AND comment_id = ? AND reporter_id <> 0", report.ArticleID, report.CommentID).
+		Where("NOT (status = ? AND action = ?)", ReportResolved, ActionDismiss).
+		Count(&reporters).Error
+
+	if err == nil && ReportsToHide > 0 && reporters >= ReportsToHide {
+		err = hideContent(tx, report, true)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// HasReported check if the user already reported the article, or the comment when it is not nil
+func (db *DB) HasReported(reporter *User, article *Article, comment *Comment) bool {
+	commentID := 0
+	if comment != nil {
+		commentID = comment.ID
+	}
+
+	var count int
+	db.Model(&Report{}).
+		Where("reporter_id = ? AND article_id = ? AND comment_id = ?", reporter.ID, article.ID, commentID).
+		Count(&count)
+
+	return count > 0
+}
+
+// GetReport get the report with the given id
+func (db *DB) GetReport(reportID int, report *Report) error {
+	return db.Scopes(reportScope).First(report, reportID).Error
+}
+
+// GetReports get the reports of the moderation queue with the status found in queryParams
+// (default: open), oldest first. They are paginated with the limit and offset params.
+func (db *DB) GetReports(queryParams url.Values) ([]Report, error) {
+	var reports []Report
+
+	status := queryParams.Get("status")
+	if status == "" {
+		status = ReportOpen
+	}
+
+	err := db.Scopes(reportScope).
+		Where("reports.status = ?", status).
+		Order("reports.created_at asc").
+		Order("reports.id asc").
+		Limit(PageSize(queryParams)).
+		Offset(PageOffset(queryParams)).
+		Find(&reports).Error
+
+	return reports, err
+}
+
+// ClaimReport assign the report to the given moderator
+func (db *DB) ClaimReport(report *Report, moderator *User) error {
+	now := time.Now()
+
+	// The status is checked by the update itself so two moderators can't claim the same report
+	query := db.Model(&Report{}).
+		Where("id = ? AND (status = ? OR (status = ? AND moderator_id = ?))", report.ID, ReportOpen, ReportClaimed, moderator.ID).
+		Updates(map[string]interface{}{"status": ReportClaimed, "moderator_id": moderator.ID, "claimed_at": now})
+
+	if query.Error != nil {
+		return query.Error
+	}
+
+	if query.RowsAffected == 0 {
+		return errorReportNotClaimable
+	}
+
+	return db.GetReport(report.ID, report)
+}
+
+// ResolveReport apply the moderation action to the reported content and resolve
+// every pending report of this content.
+func (db *DB) ResolveReport(report *Report, moderator *User, action string) error {
+	now := time.Now()
+	tx := db.Begin()
+
+	query := tx.Model(&Report{}).
+		Where("id = ? AND (status = ? OR (status = ? AND moderator_id = ?))", report.ID, ReportOpen, ReportClaimed, moderator.ID).
+		Updates(map[string]interface{}{"status": ReportResolved, "action": action, "moderator_id": moderator.ID, "resolved_at": now})
+
+	if query.Error != nil {
+		tx.Rollback()
+		return query.Error
+	}
+
+	if query.RowsAffected == 0 {
+		tx.Rollback()
+		return errorReportNotResolvable
+	}
+
+	err := tx.Model(&Report{}).
+		Where("article_id = ? AND comment_id = ? AND status != ?", report.ArticleID, report.CommentID, ReportResolved).
+		Updates(map[string]interface{}{"status": ReportResolved, "action": action, "moderator_id": moderator.ID, "resolved_at": now}).Error
+
+	if err == nil {
+		switch action {
+		case ActionDismiss:
+			err = hideContent(tx, report, false)
+		case ActionHide:
+			err = hideContent(tx, report, true)
+		case ActionSuspend:
+			if err = hideContent(tx, report, true); err == nil {
+				err = tx.Model(&User{}).
+					Where("id = ? AND suspended_at IS NULL", report.AuthorID).
+					UpdateColumn("suspended_at", now).Error
+			}
+		}
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	return db.GetReport(report.ID, report)
+}
+
+// ValidateReports check the query string params of the moderation queue
+// It returns nil when they are valid.
+func ValidateReports(queryParams url.Values) ValidationErrors {
+	if status := queryParams.Get("status"); status != "" && !contains(reportStatuses, status) {
+		return ValidationErrors{"status": []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(reportStatuses, ", "))}}
+	}
+
+	return nil
+}
+
+// ValidateModerationAction check the action resolving a report
+// It returns nil when it is valid.
+func ValidateModerationAction(action string) ValidationErrors {
+	if !contains(moderationActions, action) {
+		return ValidationErrors{"action": []string{fmt.Sprintf(NOT_IN_LIST_MSG, strings.Join(moderationActions, ", "))}}
+	}
+
+	return nil
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Scopes															 		 //
+///////////////////////////////////////////////////////////////////////////////
+
+// Eager load the users and the reported content of the reports,
+// the hidden content is loaded too.
+func reportScope(db *gorm.DB) *gorm.DB {
+	return db.Preload("Reporter").
+		Preload("Moderator").
+		Preload("Article").
+		Preload("Article.User").
+		Preload("Comment").
+		Preload("Comment.User")
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// hideContent hide the reported content, or show it again when hidden is false.
+// The hidden comments are not counted in the comments count of their article.
+func hideContent(db *gorm.DB, report *Report, hidden bool) error {
+	var hiddenAt interface{}
+	condition, delta := "hidden_at IS NOT NULL", 1
+	if hidden {
+		hiddenAt, condition, delta = time.Now(), "hidden_at IS NULL", -1
+	}
+
+	if !report.IsComment() {
+		return db.Unscoped().Model(&Article{}).
+			Where("id = ? AND "+condition, report.ArticleID).
+			UpdateColumn("hidden_at", hiddenAt).Error
+	}
+
+	// The comments in the trash are already uncounted, they are left as they are
+	query := db.Model(&Comment{}).
+		Where("id = ? AND "+condition, report.CommentID).
+		UpdateColumn("hidden_at", hiddenAt)
+
+	if query.Error != nil || query.RowsAffected == 0 {
+		return query.Error
+	}
+
+	return incrementCommentsCount(db, report.ArticleID, delta)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
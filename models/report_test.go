@@ -0,0 +1,57 @@
+package models
+
+import "testing"
+
+func TestNewReport(t *testing.T) {
+	reporter := &User{ID: 1}
+	a := &Article{ID: 1, UserID: 2}
+	c := &Comment{ID: 1, UserID: 3}
+
+	tests := []struct {
+		name       string
+		comment    *Comment
+		reason     string
+		wantAuthor int
+		wantErr    bool
+	}{
+		{"report an article", nil, "spam", 2, false},
+		{"report a comment", c, "harassment", 3, false},
+		{"report with an unknown reason", nil, "boring", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, errs := NewReport(reporter, a, tt.comment, tt.reason, "")
+			if (errs != nil) != tt.wantErr {
+				t.Errorf("NewReport() error = %v, wantErr %v", errs, tt.wantErr)
+				return
+			}
+			if report != nil && report.AuthorID != tt.wantAuthor {
+				t.Errorf("NewReport() AuthorID = %v, want %v", report.AuthorID, tt.wantAuthor)
+			}
+		})
+	}
+}
+
+func TestReportCanBeClaimedBy(t *testing.T) {
+	moderator := &User{ID: 1, Role: RoleModerator}
+
+	tests := []struct {
+		name   string
+		report *Report
+		want   bool
+	}{
+		{"open report", &Report{Status: ReportOpen}, true},
+		{"report claimed by the moderator", &Report{Status: ReportClaimed, ModeratorID: 1}, true},
+		{"report claimed by another moderator", &Report{Status: ReportClaimed, ModeratorID: 2}, false},
+		{"resolved report", &Report{Status: ReportResolved, ModeratorID: 1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.report.CanBeClaimedBy(moderator); got != tt.want {
+				t.Errorf("CanBeClaimedBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
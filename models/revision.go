@@ -0,0 +1,185 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+type RevisionStorer interface {
+	GetRevisions(*Article, *[]Revision) error
+	GetRevision(*Article, int, *Revision) error
+	SaveArticleRevision(*Article, *User) error
+	RestoreRevision(*Article, *Revision, *User) error
+}
+
+// Revision is an immutable snapshot of an article content,
+// recorded every time the article is created or edited.
+type Revision struct {
+	ID        int
+	Article   Article
+	ArticleID int `gorm:"unique_index:index_revisions_on_article_id_and_number"`
+	User      User
+	UserID    int
+	// Number is the position of the revision in the history of its article, starting at 1
+	Number      int `gorm:"unique_index:index_revisions_on_article_id_and_number"`
+	Title       string
+	Description string
+	Body        string `gorm:"type:text"`
+	// Changes is the comma separated list of the fields changed by this revision
+	Changes string
+	// RestoredFrom is the number of the revision this one restored, if any
+	RestoredFrom int
+	CreatedAt    time.Time
+}
+
+// revisionFields are the article fields tracked by the revisions
+var revisionFields = []string{"title", "description", "body"}
+
+// NewRevision returns the revision of the current content of the article, edited by editor
+func NewRevision(article *Article, editor *User, number int, changes []string) *Revision {
+	return &Revision{
+		ArticleID:   article.ID,
+		UserID:      editor.ID,
+		Number:      number,
+		Title:       article.Title,
+		Description: article.Description,
+		Body:        article.Body,
+		Changes:     strings.Join(changes, ","),
+	}
+}
+
+// ChangedFields returns the fields changed by this revision
+func (r *Revision) ChangedFields() []string {
+	if r.Changes == "" {
+		return []string{}
+	}
+
+	return strings.Split(r.Changes, ",")
+}
+
+// Field returns the value of a tracked field
+func (r *Revision) Field(name string) string {
+	switch name {
+	case "title":
+		return r.Title
+	case "description":
+		return r.Description
+	case "body":
+		return r.Body
+	}
+
+	return ""
+}
+
+// GetRevisions get all revisions of the given article, oldest first
+func (db *DB) GetRevisions(article *Article, revisions *[]Revision) error {
+	return db.Preload("User").
+		Where("article_id = ?", article.ID).
+		Order("number asc").
+		Find(revisions).Error
+}
+
+// GetRevision get the revision of the given article with the given number
+func (db *DB) GetRevision(article *Article, number int, revision *Revision) error {
+	return db.Preload("User").
+		Where("article_id = ? AND number = ?", article.ID, number).
+		First(revision).Error
+}
+
+// editedArticleColumns are the columns of an article written when it is edited,
+// along with the ones its callbacks derive from the content.
+var editedArticleColumns = []string{
+	"slug", "title", "description", "body", "body_html", "word_count",
+	"reading_time", "excerpt", "status", "published_at", "updated_at",
+}
+
+// SaveArticleRevision save/update an article and record its new content
+// as a revision edited by editor, when the content changed.
+func (db *DB) SaveArticleRevision(article *Article, editor *User) error {
+	return db.saveArticleRevision(article, editor, 0)
+}
+
+// RestoreRevision set the article content back to the given revision,
+// the restoration is recorded as a new revision.
+func (db *DB) RestoreRevision(article *Article, revision *Revision, editor *User) error {
+	article.Title = revision.Title
+	article.Description = revision.Description
+	article.Body = revision.Body
+
+	return db.saveArticleRevision(article, editor, revision.Number)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+func (db *DB) saveArticleRevision(article *Article, editor *User, restoredFrom int) error {
+	tx := db.Begin()
+
+	var previous Article
+	if err := tx.Preload("User").First(&previous, article.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The numbers follow the last one, a purged revision leaves a gap that is never reused
+	var last int
+	err := tx.Model(&Revision{}).
+		Where("article_id = ?", article.ID).
+		Select("COALESCE(MAX(number), 0)").
+		Row().
+		Scan(&last)
+
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Articles created before the revisions existed get their
+	// previous content recorded first so it can be restored.
+	if last == 0 {
+		last++
+		initial := NewRevision(&previous, &previous.User, last, revisionFields)
+		initial.CreatedAt = previous.UpdatedAt
+		if err := tx.Create(initial).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// Only the edited columns are written, the counters loaded with the article may be stale
+	if err := tx.Select(editedArticleColumns).Save(article).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if changes := changedFields(&previous, article); len(changes) > 0 {
+		revision := NewRevision(article, editor, last+1, changes)
+		revision.RestoredFrom = restoredFrom
+		if err := tx.Create(revision).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// changedFields returns the tracked fields whose value differs between the articles
+func changedFields(from *Article, to *Article) []string {
+	var changes []string
+
+	if from.Title != to.Title {
+		changes = append(changes, "title")
+	}
+
+	if from.Description != to.Description {
+		changes = append(changes, "description")
+	}
+
+	if from.Body != to.Body {
+		changes = append(changes, "body")
+	}
+
+	return changes
+}
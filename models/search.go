@@ -0,0 +1,387 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jinzhu/gorm"
+)
+
+type SearchStorer interface {
+	SearchArticles(string, *User, int, int) ([]SearchResult, int, error)
+	ReindexArticles() error
+}
+
+// SearchIndex is the full text search engine used for a dialect.
+type SearchIndex interface {
+	// Setup create the index structures, it is called by InitSchema
+	Setup(*gorm.DB) error
+	// Index add or replace the article in the index
+	Index(*gorm.DB, *Article) error
+	// Remove delete the article from the index
+	Remove(*gorm.DB, *Article) error
+	// Search returns the hits for the given terms among the articles selected by query, best matches first.
+	// The limit and offset are applied to the selected articles.
+	Search(query *gorm.DB, terms []string, limit int, offset int) ([]SearchHit, error)
+	// Count returns the number of articles selected by query matching the given terms
+	Count(query *gorm.DB, terms []string) (int, error)
+}
+
+// SearchHit is an article matching a search, as returned by a SearchIndex.
+// The snippet highlights are delimited by highlightStart and highlightEnd.
+type SearchHit struct {
+	ArticleID int
+	Score     float64
+	Snippet   string
+}
+
+// SearchResult is an article matching a search, the snippet is HTML escaped
+// and the matched terms are wrapped into <mark></mark> tags.
+type SearchResult struct {
+	Article
+	Score   float64
+	Snippet string
+}
+
+const (
+	highlightStart = "\x02"
+	highlightEnd   = "\x03"
+	snippetLength  = 160
+)
+
+// searchIndexes are the search indexes by dialect name,
+// dialects without one use a LIKE based index.
+var searchIndexes = map[string]SearchIndex{
+	"sqlite3": sqliteSearchIndex{},
+}
+
+// RegisterSearchIndex use the given search index for the given dialect name
+func RegisterSearchIndex(dialect string, index SearchIndex) {
+	searchIndexes[dialect] = index
+}
+
+// SearchArticles search the listed articles matching every words of query, best matches first.
+// The articles of the users hidden from u are left out. It also returns the number of matching articles.
+func (db *DB) SearchArticles(query string, u *User, limit int, offset int) ([]SearchResult, int, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	index := searchIndexFor(db.DB)
+	listed := db.ExcludeHiddenAuthors(db.Model(&Article{}).Scopes(listedArticleScope), u)
+
+	total, err := index.Count(listed, terms)
+	if err != nil || total == 0 {
+		return nil, 0, err
+	}
+
+	hits, err := index.Search(listed, terms, limit, offset)
+	if err != nil || len(hits) == 0 {
+		return nil, total, err
+	}
+
+	var ids []int
+	for _, hit := range hits {
+		ids = append(ids, hit.ArticleID)
+	}
+
+	articles, err := db.GetArticlesByID(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byID := map[int]Article{}
+	for _, a := range articles {
+		byID[a.ID] = a
+	}
+
+	var results []SearchResult
+	for _, hit := range hits {
+		if a, ok := byID[hit.ArticleID]; ok {
+			results = append(results, SearchResult{Article: a, Score: hit.Score, Snippet: highlight(hit.Snippet)})
+		}
+	}
+
+	return results, total, nil
+}
+
+// ReindexArticles rebuild the search index from the articles table
+func (db *DB) ReindexArticles() error {
+	var articles []Article
+	if err := db.Find(&articles).Error; err != nil {
+		return err
+	}
+
+	index := searchIndexFor(db.DB)
+	for i := range articles {
+		if err := index.Index(db.DB, &articles[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateSearch check the search query string params
+// It returns nil when they are valid.
+func ValidateSearch(queryParams url.Values) ValidationErrors {
+	if strings.TrimSpace(queryParams.Get("q")) == "" {
+		return ValidationErrors{"q": []string{EMPTY_MSG}}
+	}
+
+	return nil
+}
+
+// Callbacks
+
+// AfterSave gorm callback
+// Keep the search index up to date
+func (a *Article) AfterSave(db *gorm.DB) (err error) {
+	return searchIndexFor(db).Index(db, a)
+}
+
+// AfterDelete gorm callback
+// Remove the article from the search index
+func (a *Article) AfterDelete(db *gorm.DB) (err error) {
+	return searchIndexFor(db).Remove(db, a)
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// SQLite FTS5 index														 //
+///////////////////////////////////////////////////////////////////////////////
+
+// sqliteSearchIndex use a FTS5 virtual table whose rowid is the article id.
+// FTS5 must be compiled in the sqlite driver (go build -tags fts5).
+type sqliteSearchIndex struct{}
+
+func (sqliteSearchIndex) Setup(db *gorm.DB) error {
+	return db.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS articles_search USING fts5(title, description, body, tokenize = 'porter unicode61')").Error
+}
+
+func (idx sqliteSearchIndex) Index(db *gorm.DB, a *Article) error {
+	if err := idx.Remove(db, a); err != nil {
+		return err
+	}
+
+	return db.Exec("INSERT INTO articles_search (rowid, title, description, body) VALUES (?, ?, ?, ?)",
+		a.ID, a.Title, a.Description, a.Body).Error
+}
+
+func (sqliteSearchIndex) Remove(db *gorm.DB, a *Article) error {
+	return db.Exec("DELETE FROM articles_search WHERE rowid = ?", a.ID).Error
+}
+
+func (idx sqliteSearchIndex) Search(query *gorm.DB, terms []string, limit int, offset int) ([]SearchHit, error) {
+	var hits []SearchHit
+	// bm25 returns better matches as lower values, title and description weight more than body.
+	err := idx.match(query, terms).
+		Select(`articles.id AS article_id, -bm25(articles_search, 10.0, 5.0, 1.0) AS score,
+			snippet(articles_search, -1, ?, ?, '...', 24) AS snippet`, highlightStart, highlightEnd).
+		Order("score DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error
+
+	return hits, err
+}
+
+func (idx sqliteSearchIndex) Count(query *gorm.DB, terms []string) (int, error) {
+	var count int
+	err := idx.match(query, terms).Count(&count).Error
+	return count, err
+}
+
+// match join the index to the articles selected by query and keep the ones matching the terms.
+// Every term is quoted so the user input can't use the FTS5 query syntax, and matched as a prefix.
+func (sqliteSearchIndex) match(query *gorm.DB, terms []string) *gorm.DB {
+	var quoted []string
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.Replace(term, `"`, `""`, -1)+`"*`)
+	}
+
+	return query.Joins("JOIN articles_search ON articles_search.rowid = articles.id").
+		Where("articles_search MATCH ?", strings.Join(quoted, " "))
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// LIKE index																 //
+///////////////////////////////////////////////////////////////////////////////
+
+// likeSearchIndex is the portable fallback, it needs no index structure
+// and scores title matches over description matches over body matches.
+type likeSearchIndex struct{}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (likeSearchIndex) Setup(db *gorm.DB) error              { return nil }
+func (likeSearchIndex) Index(db *gorm.DB, a *Article) error  { return nil }
+func (likeSearchIndex) Remove(db *gorm.DB, a *Article) error { return nil }
+
+func (idx likeSearchIndex) Search(query *gorm.DB, terms []string, limit int, offset int) ([]SearchHit, error) {
+	var scores []string
+	var scoreArgs []interface{}
+
+	for _, term := range terms {
+		pattern := likePattern(term)
+		scores = append(scores, `(CASE WHEN LOWER(articles.title) LIKE ? ESCAPE '\' THEN 3 ELSE 0 END +
+			CASE WHEN LOWER(articles.description) LIKE ? ESCAPE '\' THEN 2 ELSE 0 END +
+			CASE WHEN LOWER(articles.body) LIKE ? ESCAPE '\' THEN 1 ELSE 0 END)`)
+		scoreArgs = append(scoreArgs, pattern, pattern, pattern)
+	}
+
+	var rows []struct {
+		ArticleID   int
+		Score       float64
+		Title       string
+		Description string
+		Body        string
+	}
+
+	selected := fmt.Sprintf("articles.id AS article_id, %v AS score, articles.title, articles.description, articles.body", strings.Join(scores, " + "))
+
+	err := idx.match(query, terms).
+		Select(selected, scoreArgs...).
+		Order("score DESC").
+		Order("articles.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Scan(&rows).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []SearchHit
+	for _, row := range rows {
+		hits = append(hits, SearchHit{
+			ArticleID: row.ArticleID,
+			Score:     row.Score,
+			Snippet:   snippet(terms, row.Body, row.Description, row.Title),
+		})
+	}
+
+	return hits, nil
+}
+
+func (idx likeSearchIndex) Count(query *gorm.DB, terms []string) (int, error) {
+	var count int
+	err := idx.match(query, terms).Count(&count).Error
+	return count, err
+}
+
+// match keep the articles selected by query whose title, description or body contain every term
+func (likeSearchIndex) match(query *gorm.DB, terms []string) *gorm.DB {
+	for _, term := range terms {
+		pattern := likePattern(term)
+		query = query.Where(`LOWER(articles.title) LIKE ? ESCAPE '\' OR LOWER(articles.description) LIKE ? ESCAPE '\' OR LOWER(articles.body) LIKE ? ESCAPE '\'`,
+			pattern, pattern, pattern)
+	}
+
+	return query
+}
+
+func likePattern(term string) string {
+	return "%" + likeEscaper.Replace(strings.ToLower(term)) + "%"
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+func searchIndexFor(db *gorm.DB) SearchIndex {
+	if index, ok := searchIndexes[db.Dialect().GetName()]; ok {
+		return index
+	}
+
+	return likeSearchIndex{}
+}
+
+// setupSearchIndex create the search index for the dialect of db,
+// the LIKE index is used instead when it is not available (e.g. FTS5 not compiled in).
+func setupSearchIndex(db *gorm.DB) {
+	dialect := db.Dialect().GetName()
+
+	if err := searchIndexFor(db).Setup(db); err != nil {
+		RegisterSearchIndex(dialect, likeSearchIndex{})
+	}
+}
+
+// snippet extract an excerpt around the first term found in one of texts,
+// the terms found in the excerpt are delimited by highlightStart and highlightEnd.
+func snippet(terms []string, texts ...string) string {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		if len(lower) != len(text) {
+			// Some runes change their length when lowered, offsets would not match
+			lower = text
+		}
+
+		var matches [][2]int
+		for _, term := range terms {
+			term = strings.ToLower(term)
+			for from := 0; ; {
+				i := strings.Index(lower[from:], term)
+				if i < 0 {
+					break
+				}
+				matches = append(matches, [2]int{from + i, from + i + len(term)})
+				from += i + len(term)
+			}
+		}
+
+		if len(matches) == 0 {
+			continue
+		}
+
+		sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+
+		start := matches[0][0] - snippetLength/2
+		if start < 0 {
+			start = 0
+		}
+		for start > 0 && !utf8.RuneStart(text[start]) {
+			start--
+		}
+
+		end := start + snippetLength
+		if end > len(text) {
+			end = len(text)
+		}
+		for end < len(text) && !utf8.RuneStart(text[end]) {
+			end++
+		}
+
+		var excerpt []string
+		if start > 0 {
+			excerpt = append(excerpt, "...")
+		}
+
+		pos := start
+		for _, m := range matches {
+			if m[0] < pos || m[1] > end {
+				continue
+			}
+			excerpt = append(excerpt, text[pos:m[0]], highlightStart, text[m[0]:m[1]], highlightEnd)
+			pos = m[1]
+		}
+		excerpt = append(excerpt, text[pos:end])
+
+		if end < len(text) {
+			excerpt = append(excerpt, "...")
+		}
+
+		return strings.Join(excerpt, "")
+	}
+
+	return ""
+}
+
+// highlight HTML escape a snippet and turn its highlight delimiters into <mark></mark> tags
+func highlight(snippet string) string {
+	return strings.NewReplacer(highlightStart, "<mark>", highlightEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
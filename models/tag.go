@@ -1,9 +1,12 @@
 package models
 
+import "github.com/jinzhu/gorm"
+
 type TagStorer interface {
 	FindTag(*Tag) error
 	FindTags(tags *[]Tag) error
 	FindTagOrInit(string) (Tag, error)
+	RefreshTaggingsCounts() error
 }
 
 type Tag struct {
@@ -13,6 +16,11 @@ type Tag struct {
 	Articles      []Article `gorm:"many2many:taggings;"`
 }
 
+// taggingsCountQuery set the taggings count of the tags to the number of articles out of the trash tagged with them
+const taggingsCountQuery = `UPDATE tags SET taggings_count = (SELECT COUNT(*) FROM taggings
+	JOIN articles ON articles.id = taggings.article_id
+	WHERE taggings.tag_id = tags.id AND articles.deleted_at IS NULL)`
+
 func (db *DB) FindTag(tag *Tag) error {
 	return db.Where(&tag).Find(&tag).Error
 }
@@ -25,3 +33,27 @@ func (db *DB) FindTagOrInit(tagName string) (tag Tag, err error) {
 	err = db.DB.FirstOrInit(&tag, Tag{Name: tagName}).Error
 	return
 }
+
+// RefreshTaggingsCounts recount the taggings of every tag
+func (db *DB) RefreshTaggingsCounts() error {
+	return db.Exec(taggingsCountQuery).Error
+}
+
+///////////////////////////////////////////////////////////////////////////////
+// Private Methods															 //
+///////////////////////////////////////////////////////////////////////////////
+
+// refreshTaggingsCount recount the taggings of the given tags
+func refreshTaggingsCount(db *gorm.DB, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	return db.Exec(taggingsCountQuery+" WHERE tags.id IN (?)", tagIDs).Error
+}
+
+// tagIDsOf returns the ids of the tags of the given article
+func tagIDsOf(db *gorm.DB, articleID int) (ids []uint, err error) {
+	err = db.Table("taggings").Where("article_id = ?", articleID).Pluck("tag_id", &ids).Error
+	return
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+type TrashStorer interface {
+	GetTrashedArticles(*User, time.Time) ([]Article, error)
+	GetTrashedComments(*User, time.Time) ([]Comment, error)
+	RestoreArticle(*User, string, time.Time) (*Article, error)
+	RestoreComment(*User, int, time.Time) (*Comment, error)
+	PurgeTrash(time.Time) (int64, error)
+}
+
+// TrashRetention is how long the deleted articles and comments can be restored
+// before being purged, it can be changed at startup to fit the deployment.
+var TrashRetention = 30 * 24 * time.Hour
+
+var (
+	errorMissingPrimaryKey = errors.New("Cannot delete a record without primary key")
+	// ErrorSlugTaken is returned by RestoreArticle when a live article uses the slug
+	ErrorSlugTaken = errors.New(TAKEN_MSG)
+)
+
+// PurgeAt returns when a record deleted at deletedAt is purged from the trash
+func PurgeAt(deletedAt time.Time) time.Time {
+	return deletedAt.Add(TrashRetention)
+}
+
+// GetTrashedArticles get the articles deleted by the given author that can still be restored at now,
+// last deleted first.
+func (db *DB) GetTrashedArticles(author *User, now time.Time) ([]Article, error) {
+	var articles []Article
+
+	err := db.Unscoped().
+		Preload("Tags").
+		Preload("User").
+		Where("articles.user_id = ? AND articles.deleted_at > ?", author.ID, now.Add(-TrashRetention)).
+		Order("articles.deleted_at desc").
+		Find(&articles).Error
+
+	return articles, err
+}
+
+// GetTrashedComments get the comments deleted by the given author that can still be restored at now,
+// last deleted first. Their Article is loaded even when it is deleted too.
+func (db *DB) GetTrashedComments(author *User, now time.Time) ([]Comment, error) {
+	var comments []Comment
+
+	err := db.Unscoped().
+		Preload("User").
+		Where("comments.user_id = ? AND comments.deleted_at > ?", author.ID, now.Add(-TrashRetention)).
+		Order("comments.deleted_at desc").
+		Find(&comments).Error
+
+	if err != nil || len(comments) == 0 {
+		return comments, err
+	}
+
+	var ids []int
+	for _, c := range comments {
+		ids = append(ids, c.ArticleID)
+	}
+
+	var articles []Article
+	if err := db.Unscoped().Where("id IN (?)", ids).Find(&articles).Error; err != nil {
+		return nil, err
+	}
+
+	byID := map[int]Article{}
+	for _, a := range articles {
+		byID[a.ID] = a
+	}
+
+	for i := range comments {
+		comments[i].Article = byID[comments[i].ArticleID]
+	}
+
+	return comments, nil
+}
+
+// RestoreArticle take the last article deleted by author with the given slug out of the trash,
+// if it can still be restored at now. It is not restored while another article uses its slug.
+func (db *DB) RestoreArticle(author *User, slug string, now time.Time) (*Article, error) {
+	var article Article
+
+	err := db.Unscoped().
+		Scopes(articleScope).
+		Where("articles.user_id = ? AND articles.slug = ? AND articles.deleted_at > ?", author.ID, slug, now.Add(-TrashRetention)).
+		Order("articles.deleted_at desc", true).
+		First(&article).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
+
+	var taken int
+	if err := tx.Model(&Article{}).Where("slug = ?", article.Slug).Count(&taken).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if taken > 0 {
+		tx.Rollback()
+		return nil, ErrorSlugTaken
+	}
+
+	// UpdateColumn skips the callbacks, the article is put back in the search index by hand.
+	if err := tx.Unscoped().Model(&article).UpdateColumn("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := searchIndexFor(tx).Index(tx, &article); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	tagIDs, err := tagIDsOf(tx, article.ID)
+	if err == nil {
+		err = refreshTaggingsCount(tx, tagIDs)
+	}
+
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &article, tx.Commit().Error
+}
+
+// RestoreComment take the comment deleted by author with the given id out of the trash,
+// if it can still be restored at now.
+func (db *DB) RestoreComment(author *User, commentID int, now time.Time) (*Comment, error) {
+	var comment Comment
+
+	err := db.Unscoped().
+		Preload("User").
+		Where("comments.user_id = ? AND comments.deleted_at > ?", author.ID, now.Add(-TrashRetention)).
+		First(&comment, commentID).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx := db.Begin()
+
+	// UpdateColumn skips the callbacks, the comment is counted again by hand
+	// unless it is still hidden.
+	if err := tx.Unscoped().Model(&comment).UpdateColumn("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if !comment.IsHidden() {
+		if err := incrementCommentsCount(tx, comment.ArticleID, 1); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	return &comment, tx.Commit().Error
+}
+
+// PurgeTrash permanently delete the articles and comments deleted for longer than the retention at now
+// It returns the number of purged rows.
+func (db *DB) PurgeTrash(now time.Time) (int64, error) {
+	expired := now.Add(-TrashRetention)
+
+	var ids []int
+	if err := db.Unscoped().Model(&Article{}).Where("deleted_at <= ?", expired).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, id := range ids {
+		if err := db.PurgeArticle(&Article{ID: id}); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	// The comments with replies are kept as placeholders until their replies are purged
+	purgeable := "deleted_at <= ? AND NOT " + hasRepliesQuery
+
+	tx := db.Begin()
+
+	for _, table := range []string{"comment_versions", "reports", "notifications"} {
+		err := tx.Exec("DELETE FROM "+table+" WHERE comment_id IN (SELECT id FROM comments WHERE "+purgeable+")", expired).Error
+		if err != nil {
+			tx.Rollback()
+			return purged, err
+		}
+	}
+
+	query := tx.Unscoped().Where(purgeable, expired).Delete(&Comment{})
+	if query.Error != nil {
+		tx.Rollback()
+		return purged, query.Error
+	}
+
+	return purged + query.RowsAffected, tx.Commit().Error
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type TrendingStorer interface {
+	TrendingScores(time.Time, time.Duration, time.Duration) ([]TrendingScore, error)
+	GetArticlesByID([]int) ([]Article, error)
+}
+
+// TrendingScore is the engagement an article received over the trending window
+type TrendingScore struct {
+	ArticleID int
+	Score     float64
+}
+
+// TrendingCache keeps the trending scores in memory, they are computed by
+// Refresh, either periodically with Run or on the first call to Scores.
+type TrendingCache struct {
+	// Window is how far in the past favorites and comments are taken into account
+	Window time.Duration
+	// HalfLife is the age at which a favorite or a comment weight half of a new one
+	HalfLife time.Duration
+
+	store       TrendingStorer
+	mutex       sync.RWMutex
+	scores      []TrendingScore
+	refreshedAt time.Time
+}
+
+const (
+	defaultTrendingWindow   = 7 * 24 * time.Hour
+	defaultTrendingHalfLife = 24 * time.Hour
+
+	trendingFavoriteWeight = 1.0
+	trendingCommentWeight  = 2.0
+)
+
+// NewTrendingCache returns a cache over a 7 days window with a 1 day half life.
+func NewTrendingCache(store TrendingStorer) *TrendingCache {
+	return &TrendingCache{
+		Window:   defaultTrendingWindow,
+		HalfLife: defaultTrendingHalfLife,
+		store:    store,
+	}
+}
+
+// Refresh recompute the trending scores
+func (t *TrendingCache) Refresh() error {
+	scores, err := t.store.TrendingScores(time.Now(), t.Window, t.HalfLife)
+	if err != nil {
+		return err
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.scores = scores
+	t.refreshedAt = time.Now()
+
+	return nil
+}
+
+// Run refresh the trending scores every interval until stop is closed.
+// Refresh errors are passed to onError, the previous scores are kept meanwhile.
+func (t *TrendingCache) Run(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	RunEvery(interval, stop, t.Refresh, onError)
+}
+
+// Scores returns the cached trending scores, best first
+func (t *TrendingCache) Scores() ([]TrendingScore, error) {
+	t.mutex.RLock()
+	refreshed := !t.refreshedAt.IsZero()
+	t.mutex.RUnlock()
+
+	if !refreshed {
+		if err := t.Refresh(); err != nil {
+			return nil, err
+		}
+	}
+
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+
+	return t.scores, nil
+}
+
+// TrendingScores compute the articles scores from the favorites and comments created
+// within window before now, each of them losing half of its weight every halfLife.
+func (db *DB) TrendingScores(now time.Time, window time.Duration, halfLife time.Duration) ([]TrendingScore, error) {
+	since := now.Add(-window)
+
+	var favorites, comments []struct {
+		ArticleID int
+		CreatedAt time.Time
+	}
+
+	// Only the published articles that are neither hidden nor in the trash can trend
+	live := "article_id IN (SELECT id FROM articles WHERE status = ? AND hidden_at IS NULL AND deleted_at IS NULL)"
+
+	if err := db.Table("favorites").Select("article_id, created_at").Where("created_at > ? AND "+live, since, StatusPublished).Scan(&favorites).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Table("comments").Select("article_id, created_at").Where("created_at > ? AND deleted_at IS NULL AND "+live, since, StatusPublished).Scan(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	decay := func(createdAt time.Time) float64 {
+		return math.Pow(0.5, now.Sub(createdAt).Hours()/halfLife.Hours())
+	}
+
+	byArticle := map[int]float64{}
+
+	for _, f := range favorites {
+		byArticle[f.ArticleID] += trendingFavoriteWeight * decay(f.CreatedAt)
+	}
+
+	for _, c := range comments {
+		byArticle[c.ArticleID] += trendingCommentWeight * decay(c.CreatedAt)
+	}
+
+	var scores []TrendingScore
+	for articleID, score := range byArticle {
+		scores = append(scores, TrendingScore{ArticleID: articleID, Score: score})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score == scores[j].Score {
+			return scores[i].ArticleID > scores[j].ArticleID
+		}
+		return scores[i].Score > scores[j].Score
+	})
+
+	return scores, nil
+}
@@ -20,6 +20,27 @@ type User struct {
 	Password  string
 	Bio       string
 	Image     string
+	Role      string
+	// SuspendedAt is when the moderators suspended the user, nil when they are not
+	SuspendedAt *time.Time
+}
+
+// User roles, the moderators can see what the other users can't
+// (e.g. the previous versions of the comments).
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+)
+
+// IsModerator check if the user has the moderator role
+func (u *User) IsModerator() bool {
+	return u.Role == RoleModerator
+}
+
+// IsSuspended check if the moderators suspended the user,
+// a suspended user can still read but can't post anymore.
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
 }
 
 func (u *User) MatchPassword(password string) bool {
@@ -75,7 +96,7 @@ func (db *DB) FindUserByEmail(email string) (*User, error) {
 	u := User{}
 	db.Find(&u, "email = ?", email)
 	if u == (User{}) {
-		return nil, fmt.Errorf("No user found with userame: ", email)
+		return nil, fmt.Errorf("No user found with email: %v", email)
 	}
 	return &u, nil
 }